@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,32 +16,115 @@ import (
 )
 
 func main() {
+	report := flag.String("report", "", "print the journal's PnL report of the given day (YYYY-MM-DD) and exit")
+	dryRun := flag.Bool("dry-run", false, "simulate swaps and paper trade the CEX leg instead of moving funds")
+	flag.Parse()
+
 	// Load the .env file
 	err := godotenv.Load(".env")
 	if err != nil {
 		log.Fatalf("Error loading .env file")
 	}
 
+	// Paper arbs are kept apart, so they are not reported as trades or resumed as live arbs
+	journalPath := src.JournalPathFromEnv()
+	if *dryRun {
+		journalPath += "-paper"
+	}
+	journal, err := src.OpenJournal(journalPath)
+	if err != nil {
+		log.Fatalf("Error opening journal: %v", err)
+	}
+	defer journal.Close()
+
+	if *report != "" {
+		if err := printDailyReport(journal, *report); err != nil {
+			log.Fatalf("Error building report: %v", err)
+		}
+		return
+	}
+
 	seedConfig, err := src.OsmosisInit()
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	// Set up a ticker to run the function every minute
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+	arbConfig, err := src.LoadArbConfig(seedConfig)
+	if err != nil {
+		log.Fatalf("Error loading arb config: %v", err)
+	}
+	seedConfig.RouteIntermediateDenoms = arbConfig.RouteIntermediateDenoms
+
+	binanceVenue := src.NewBinanceVenueFromEnv()
+	binanceVenue.SetMarketDataStaleAfter(arbConfig.MarketDataStaleAfter())
 
-	for {
-		// Execute the function
-		err = runArbitrageCheck(seedConfig)
-		fmt.Println(err)
+	var venue src.CEXVenue = binanceVenue
+	if *dryRun {
+		log.Println("Dry run: swaps are only simulated and CEX orders are paper traded")
+		seedConfig.EnableDryRun()
+		venue = src.NewPaperVenue(venue, arbConfig.Pairs)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go src.ServeMetrics(ctx, src.MetricsAddressFromEnv())
+
+	// Paper balances and orders are simulated, the paper venue doesn't stream them
+	if watcher, ok := venue.(src.AccountWatcher); ok {
+		go watcher.WatchAccount(ctx)
+	}
 
-		// Wait for the next tick
-		<-ticker.C
+	// Paper balances can't be moved between venues
+	if !*dryRun {
+		go src.RunRebalancer(ctx, seedConfig, venue, arbConfig.Rebalance, journal)
 	}
+
+	// Evaluate the arb on every new Osmosis block and every CEX book update
+	scheduler := src.NewScheduler(arbConfig.Debounce())
+
+	go src.WatchOsmosisBlocks(ctx, seedConfig, arbConfig.BlockPollInterval(), func(height int64) {
+		scheduler.Trigger(fmt.Sprintf("osmosis block %d", height))
+	})
+
+	if watcher, ok := venue.(src.BookTickerWatcher); ok {
+		go watcher.WatchBookTicker(ctx, arbConfig.CEXSymbols(), func(symbol string) {
+			scheduler.Trigger(venue.Name() + " " + symbol + " book ticker")
+		})
+	}
+
+	scheduler.Trigger("startup")
+	scheduler.Run(ctx, func(string) {
+		err := runArbitrageCheck(ctx, seedConfig, venue, arbConfig, journal)
+		if err != nil {
+			fmt.Println(err)
+		}
+	})
 }
 
-func runArbitrageCheck(seedConfig src.SeedConfig) error {
-	err := src.CheckArbitrage(seedConfig)
+func runArbitrageCheck(ctx context.Context, seedConfig src.SeedConfig, venue src.CEXVenue, arbConfig src.ArbConfig, journal *src.Journal) error {
+	err := src.CheckArbitrage(ctx, seedConfig, venue, arbConfig, journal)
 	return err
 }
+
+func printDailyReport(journal *src.Journal, day string) error {
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return err
+	}
+
+	reports, err := journal.DailyReport(date)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("=======Arb report for", day, "(UTC)=======")
+	if len(reports) == 0 {
+		fmt.Println("No arbs recorded")
+	}
+	for _, report := range reports {
+		fmt.Printf("%s: opportunities %d, skipped %d, executed %d, failed %d, volume %f, realized PnL %f, residual base %f\n",
+			report.Pair, report.Opportunities, report.Skipped, report.Executed, report.Failed, report.Volume, report.RealizedPnL, report.ResidualBase)
+	}
+	return nil
+}
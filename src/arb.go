@@ -1,121 +1,349 @@
 package src
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
-func CheckArbitrage(seedConfig SeedConfig) error {
-	time := getTime()
-	fmt.Println("=======Starting ARB in ", time, "=======")
+// osmosisAccountMu is held by arb evaluations and rebalancing, which both sign
+// Osmosis txs from the same account and would otherwise race for its sequence
+// and spend the balances the other sized its trades from
+var osmosisAccountMu sync.Mutex
+
+// CheckArbitrage resumes the arbs left in flight, then looks for and executes an
+// arb on every configured pair. A failure on one pair does not stop the others from being checked.
+// Opportunities are recorded in journal unless it is nil.
+func CheckArbitrage(ctx context.Context, seedConfig SeedConfig, venue CEXVenue, arbConfig ArbConfig, journal *Journal) error {
+	loopIterations.Inc()
+
+	osmosisAccountMu.Lock()
+	defer osmosisAccountMu.Unlock()
+
+	var errs []error
+
+	// arbs left in flight are settled first, their pairs are not traded until they are.
+	// Paper orders do not outlive the process, so paper arbs are never resumed.
+	var inFlight map[string]bool
+	if !seedConfig.DryRun {
+		var err error
+		inFlight, err = ResumeArbs(ctx, seedConfig, venue, arbConfig, journal)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-	btcBalance, usdtBalance, err := GetTotalBalance(seedConfig)
+	for _, pair := range arbConfig.Pairs {
+		if inFlight[pair.Name] {
+			fmt.Println("Skipping", pair.Name, "until its arb in flight settles")
+			continue
+		}
+		if err := checkPairArbitrage(ctx, seedConfig, venue, pair, journal); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pair.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkPairArbitrage(ctx context.Context, seedConfig SeedConfig, venue CEXVenue, pair TradingPair, journal *Journal) error {
+	startTime := getTime()
+	fmt.Println("=======Starting", pair.Name, "ARB in ", startTime, "=======")
+
+	balances, err := GetPairBalances(seedConfig, venue, pair)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Balance before arb is, btc: ", btcBalance, " usdt: ", usdtBalance)
+	fmt.Println("Balance before arb is", balances)
 
-	binanceBTCPrice, err := GetBinanceBTCToUSDTPrice()
+	book, err := venue.GetDepth(pair.CEXSymbol, cexDepthLimit)
 	if err != nil {
-		return fmt.Errorf("error fetching Binance BTC price: %v", err)
+		return fmt.Errorf("error fetching %s %s order book: %v", venue.Name(), pair.CEXSymbol, err)
 	}
 
-	arbAmount, err := calculateArbAmount(btcBalance, usdtBalance, binanceBTCPrice)
+	filters, err := venue.GetSymbolFilters(pair.CEXSymbol)
 	if err != nil {
-		return err
+		return fmt.Errorf("error fetching %s %s filters: %v", venue.Name(), pair.CEXSymbol, err)
 	}
-	fmt.Println("Binance BTC Price:", binanceBTCPrice)
 
-	osmosisBTCPrice, route, err := GetOsmosisBTCToUSDCPriceAndRoute(arbAmount)
+	sizing, err := sizeArb(seedConfig, venue, pair, filters, book, balances)
 	if err != nil {
-		return fmt.Errorf("error fetching Osmosis BTC price: %v", err)
+		return err
 	}
 
-	fmt.Println("Osmosis BTC Price:", osmosisBTCPrice)
+	record := ArbRecord{
+		ID:   fmt.Sprintf("%s-%d", pair.CEXSymbol, time.Now().UnixNano()),
+		Time: time.Now(),
+		Pair: pair.Name,
+	}
 
-	if binanceBTCPrice < osmosisBTCPrice*riskFactor {
-		fmt.Println("Arbitrage Opportunity: Buy BTC on Binance, Sell BTC on Osmosis")
+	// each direction is reported at the size it was probed at, or the size chosen for the arb
+	prices := sizing.probes
+	if sizing.found {
+		prices[sizing.best.direction] = sizing.best
+	}
+	if buyCEX, ok := prices[ArbDirectionBuyCEX]; ok {
+		fmt.Println(venue.Name(), "Buy Price:", buyCEX.cexPrice, "Osmosis Sell Price:", buyCEX.osmosisPrice, "for", buyCEX.amount, pair.CEXBaseAsset)
+		priceSpread.WithLabelValues(pair.Name, string(ArbDirectionBuyCEX)).Set(spreadBps(buyCEX.osmosisPrice, buyCEX.cexPrice))
+		record.CEXBuyPrice = decToFloat(buyCEX.cexPrice)
+		record.OsmosisSellPrice = decToFloat(buyCEX.osmosisPrice)
+	}
+	if sellCEX, ok := prices[ArbDirectionSellCEX]; ok {
+		fmt.Println(venue.Name(), "Sell Price:", sellCEX.cexPrice, "Osmosis Buy Price:", sellCEX.osmosisPrice, "for", sellCEX.amount, pair.CEXBaseAsset)
+		priceSpread.WithLabelValues(pair.Name, string(ArbDirectionSellCEX)).Set(spreadBps(sellCEX.cexPrice, sellCEX.osmosisPrice))
+		record.CEXSellPrice = decToFloat(sellCEX.cexPrice)
+		record.OsmosisBuyPrice = decToFloat(sellCEX.osmosisPrice)
+	}
 
-		_, route, err := GetOsmosisUSDCToBTCPriceAndRoute(arbAmount)
+	if !sizing.found {
+		fmt.Println("No arb opportunity")
+		return nil
+	}
+	best := sizing.best
+	if err := estimateFixedCosts(seedConfig, pair, &best); err != nil {
+		return fmt.Errorf("error estimating %s arb costs: %w", pair.Name, err)
+	}
+	fmt.Println("Expected profit of", best.direction, best.amount, pair.CEXBaseAsset, ":", best.profit, pair.CEXQuoteAsset)
 
-		if err != nil {
-			return err
-		}
+	arbAmount := best.amount
+	record.Direction = best.direction
+	record.ArbAmount = decToFloat(arbAmount)
+	record.ExpectedGross = decToFloat(best.profit.gross)
+	record.ExpectedCEXFee = decToFloat(best.profit.cexFee)
+	record.ExpectedPoolFees = decToFloat(best.profit.poolFees)
+	record.ExpectedGas = decToFloat(best.profit.gas)
+	record.ExpectedAuctionBid = decToFloat(best.profit.auctionBid)
+	record.ExpectedProfit = decToFloat(best.profit.net())
 
-		err = SellOsmosisBTC(seedConfig, route, binanceBTCPrice)
-		if err != nil {
-			return err
-		}
+	if !best.profit.clears(pair) {
+		fmt.Println("No arb opportunity, expected profit is below the minimum of", pair.MinProfit, pair.CEXQuoteAsset, "and", pair.MinProfitBps, "bps")
+		skipArb(journal, &record, fmt.Sprintf("expected profit below the minimum of %v %s and %v bps", pair.MinProfit, pair.CEXQuoteAsset, pair.MinProfitBps))
+		return nil
+	}
+	switch best.direction {
+	case ArbDirectionBuyCEX:
+		fmt.Println("Arbitrage Opportunity: Buy", best.amount, pair.CEXBaseAsset, "on", venue.Name(), ", Sell", pair.CEXBaseAsset, "on Osmosis")
+	case ArbDirectionSellCEX:
+		fmt.Println("Arbitrage Opportunity: Sell", best.amount, pair.CEXBaseAsset, "on", venue.Name(), ", Buy", pair.CEXBaseAsset, "on Osmosis")
+	}
 
-		_, _, err = BuyBinanceBTC(arbAmount)
-		if err != nil {
-			return err
-		}
+	trade := arbTrade{
+		osmosisQuote:   best.osmosisQuote,
+		amount:         arbAmount,
+		hedgePrice:     best.cexPrice,
+		expectedProfit: best.profit.net(),
+		auctionBid:     best.auctionBid,
+	}
 
-		btcBalance, usdtBalance, err := GetTotalBalance(seedConfig)
-		if err != nil {
-			return err
-		}
+	// a hedge the CEX would reject must be caught before the Osmosis leg goes out
+	if err := filters.ForOrderType(pair.HedgeOrderType).CheckOrder(arbAmount, trade.hedgePrice); err != nil {
+		err = fmt.Errorf("%s %s hedge of %s would be rejected: %v", venue.Name(), pair.CEXSymbol, arbAmount, err)
+		skipArb(journal, &record, err.Error())
+		return err
+	}
 
-		fmt.Println("Balance after arb is, btc: ", btcBalance, " usdt: ", usdtBalance)
+	opportunities.WithLabelValues(pair.Name, string(record.Direction)).Inc()
 
-	} else if binanceBTCPrice*riskFactor > osmosisBTCPrice {
-		fmt.Println("Arbitrage Opportunity: Sell BTC on Binance, Buy BTC on Osmosis")
+	err = executeArb(ctx, seedConfig, venue, journal, pair, trade, &record)
+	if err != nil {
+		record.Error = err.Error()
+		trades.WithLabelValues(pair.Name, string(record.Direction), tradeResultFailed).Inc()
+	} else {
+		trades.WithLabelValues(pair.Name, string(record.Direction), tradeResultExecuted).Inc()
+	}
 
-		err = BuyOsmosisBTC(seedConfig, route, binanceBTCPrice)
-		if err != nil {
-			return err
+	if journal != nil {
+		if journalErr := journal.Record(record); journalErr != nil {
+			fmt.Println("Error recording arb in journal:", journalErr)
 		}
+	}
+	if err != nil {
+		return err
+	}
 
-		_, _, err = SellBinanceBTC(arbAmount)
-		if err != nil {
-			return err
-		}
+	fmt.Println("Realized PnL:", record.RealizedPnL, pair.CEXQuoteAsset, "residual:", record.ResidualBase, pair.CEXBaseAsset)
 
-		btcBalance, usdtBalance, err := GetTotalBalance(seedConfig)
-		if err != nil {
-			return err
-		}
+	balances, err = GetPairBalances(seedConfig, venue, pair)
+	if err != nil {
+		return err
+	}
 
-		fmt.Println("Balance after arb is, btc: ", btcBalance, " usdt: ", usdtBalance)
+	fmt.Println("Balance after arb is", balances)
 
-	} else {
-		fmt.Println("No arb opportunity")
+	return nil
+}
+
+// skipArb journals an opportunity that is not traded, so the daily report counts
+// every opportunity and not only the ones traded
+func skipArb(journal *Journal, record *ArbRecord, reason string) {
+	record.State = ArbStateSkipped
+	record.SkipReason = reason
+	if journal == nil {
+		return
+	}
+	if err := journal.Record(*record); err != nil {
+		fmt.Println("Error recording skipped arb in journal:", err)
 	}
+}
 
-	return nil
+// arbTrade is an arb as sized and priced when the opportunity was found
+type arbTrade struct {
+	osmosisQuote OsmosisQuote
+	// amount is in human readable units of the base asset
+	amount sdk.Dec
+	// hedgePrice is the CEX execution price the Osmosis leg has to beat
+	hedgePrice sdk.Dec
+	// expectedProfit is net of all costs, including auctionBid, the bid sized by
+	// estimateFixedCosts, unset when the swap goes out on its own
+	expectedProfit sdk.Dec
+	auctionBid     sdk.Coin
 }
 
-// for arb amount, we use 10% of the smaller asset we have between btc and usdt
-// amount being returned is in units of btc
-func calculateArbAmount(btcBalance, usdtBalance, btcPrice float64) (float64, error) {
-	const arbPercentage = 0.1
+// spreadBps returns how far price is above reference, in bps
+func spreadBps(price, reference sdk.Dec) float64 {
+	return decToFloat(price.Sub(reference).MulInt64(10_000).Quo(reference))
+}
 
-	if btcBalance == 0 || usdtBalance == 0 {
-		return 0, fmt.Errorf("insufficient balance for arbitrage")
+// executeArb swaps on Osmosis along the trade's quote and, once the swap is confirmed,
+// hedges it on the CEX, filling record with the outcome of both legs. Every step
+// is journaled as the arb's state, see advanceArb for how a failed hedge is handled.
+func executeArb(ctx context.Context, seedConfig SeedConfig, venue CEXVenue, journal *Journal, pair TradingPair, trade arbTrade, record *ArbRecord) error {
+	var (
+		tokenInDenom string
+		hedgeSide    OrderSide
+		swap         func(SeedConfig, TradingPair, OsmosisQuote, sdk.Dec, sdk.Coin, TxSubmittedFunc) (SwapResult, error)
+	)
+	switch record.Direction {
+	case ArbDirectionBuyCEX:
+		tokenInDenom, hedgeSide, swap = pair.BaseDenom, OrderSideBuy, SellOsmosisBase
+	case ArbDirectionSellCEX:
+		tokenInDenom, hedgeSide, swap = pair.QuoteDenom, OrderSideSell, BuyOsmosisBase
+	default:
+		return fmt.Errorf("invalid arb direction %s", record.Direction)
 	}
 
-	// Calculate the BTC equivalent of the USDT balance
-	btcEquivalent := usdtBalance / btcPrice
+	record.OsmosisIn = sdk.NewCoin(tokenInDenom, routeTokenInAmount(trade.osmosisQuote.Route))
+	record.Hedge = HedgeProgress{
+		Side:       hedgeSide,
+		Quantity:   trade.amount,
+		LimitPrice: sdk.ZeroDec(),
+		Filled:     sdk.ZeroDec(),
+		Notional:   sdk.ZeroDec(),
+	}
+	e := &arbExecution{ctx: ctx, seedConfig: seedConfig, venue: venue, journal: journal, pair: pair, record: record}
+	e.transition(ArbStatePlanned)
 
-	// Calculate the arbitrage amount based on the smaller balance in BTC units
-	arbAmount := arbPercentage * min(btcBalance, btcEquivalent)
-	return arbAmount, nil
-}
+	if err := VerifyOsmosisQuote(seedConfig, pair, tokenInDenom, trade.osmosisQuote); err != nil {
+		quoteRejections.WithLabelValues(pair.Name).Inc()
+		e.transition(ArbStateCanceled)
+		return err
+	}
 
-func GetTotalBalance(seedConfig SeedConfig) (float64, float64, error) {
-	binanceBTCBalance, binanceUSDTBalance, err := GetBinanceBTCUSDTBalance()
+	// the swap is journaled as submitted before it is broadcasted, so a restart can look it up
+	result, err := swap(seedConfig, pair, trade.osmosisQuote, trade.hedgePrice, trade.auctionBid, func(hash string, timeoutHeight uint64) error {
+		record.OsmosisTxHash = hash
+		record.OsmosisTimeoutHeight = timeoutHeight
+		return e.setState(ArbStateOsmosisSubmitted)
+	})
 	if err != nil {
-		return 0, 0, fmt.Errorf("error fetching Binance balance: %v", err)
+		// a swap that may have been broadcasted stays in flight and is settled by ResumeArbs
+		if record.State == ArbStatePlanned {
+			e.transition(ArbStateCanceled)
+		}
+		return err
 	}
 
-	osmosisBTCBalance, osmosisUSDTBalance, err := GetOsmosisBTCUSDTBalance(seedConfig)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error fetching Osmosis balance: %v", err)
+	return settleOsmosisLeg(e, result)
+}
+
+// makerPrice returns the most aggressive price up to limit that does not cross the book
+func makerPrice(book OrderBook, side OrderSide, limit, tickSize sdk.Dec) sdk.Dec {
+	if !isSetDec(tickSize) {
+		tickSize = sdk.ZeroDec()
 	}
 
-	return binanceBTCBalance + osmosisBTCBalance, binanceUSDTBalance + osmosisUSDTBalance, nil
+	switch {
+	case side == OrderSideBuy && len(book.Asks) > 0:
+		return sdk.MinDec(limit, book.Asks[0].Price.Sub(tickSize))
+	case side == OrderSideSell && len(book.Bids) > 0:
+		return sdk.MaxDec(limit, book.Bids[0].Price.Add(tickSize))
+	}
+	return limit
+}
+
+// recordSwapResult fills record with the Osmosis leg, in human readable units
+func recordSwapResult(seedConfig SeedConfig, pair TradingPair, result SwapResult, record *ArbRecord) {
+	record.OsmosisTxHash = result.TxHash
+	record.OsmosisTxStatus = result.Status
+	record.GasUsed = result.GasUsed
+	record.TxFees = result.Fees.String()
+	if result.GasUsed > 0 {
+		gasUsed.WithLabelValues(pair.Name).Observe(float64(result.GasUsed))
+	}
+
+	tokenInExponent, tokenOutExponent := pair.BaseExponent, pair.QuoteExponent
+	if record.Direction == ArbDirectionSellCEX {
+		tokenInExponent, tokenOutExponent = pair.QuoteExponent, pair.BaseExponent
+	}
+	record.OsmosisTokenIn = decToFloat(fromBaseUnits(result.TokenIn.Amount, tokenInExponent))
+	record.OsmosisTokenOut = decToFloat(fromBaseUnits(result.TokenOut.Amount, tokenOutExponent))
+
+	// the bid is only paid when the swap it carries is included
+	costs := result.Fees
+	if result.AuctionBid.IsValid() && !result.AuctionBid.IsZero() {
+		record.AuctionBid = result.AuctionBid.String()
+		auctionBid.WithLabelValues(pair.Name, result.AuctionBid.Denom).Set(decToFloat(sdk.NewDecFromInt(result.AuctionBid.Amount)))
+		costs = costs.Add(result.AuctionBid)
+	}
+
+	for _, coin := range costs {
+		value, err := convertDenomToQuote(seedConfig, pair, coin)
+		if err != nil {
+			fmt.Println("Error valuing", coin, "in", pair.CEXQuoteAsset, ":", err)
+			continue
+		}
+		record.OsmosisCosts += decToFloat(value)
+	}
+}
+
+// cexCommissionInQuote values the CEX commissions of record in the pair's quote
+func cexCommissionInQuote(venue CEXVenue, pair TradingPair, record ArbRecord) float64 {
+	var total float64
+	for asset, commission := range record.CEXCommissions {
+		switch asset {
+		case pair.CEXQuoteAsset:
+			total += commission
+			continue
+		case pair.CEXBaseAsset:
+			total += commission * record.CEXFillPrice
+			continue
+		}
+
+		price, err := venue.GetPrice(asset + pair.CEXQuoteAsset)
+		if err != nil {
+			fmt.Println("Error valuing", asset, "commission in", pair.CEXQuoteAsset, ":", err)
+			continue
+		}
+		total += commission * decToFloat(price)
+	}
+	return total
+}
+
+// checkOsmosisLegConfirmed returns an error unless the Osmosis swap executed,
+// in which case the CEX hedge must not be placed
+func checkOsmosisLegConfirmed(result TxResult) error {
+	switch result.Status {
+	case TxStatusIncluded, TxStatusSimulated:
+		return nil
+	case TxStatusFailed:
+		return fmt.Errorf("osmosis swap %s failed with code %d: %s", result.TxHash, result.Code, result.Log)
+	default:
+		return fmt.Errorf("osmosis swap %s was not executed: %s", result.TxHash, result.Status)
+	}
 }
 
 func getTime() string {
@@ -0,0 +1,592 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// ArbState is the step an arb's execution has reached. Every transition is
+// journaled, so an arb interrupted by a restart is resumed where it stopped.
+type ArbState string
+
+const (
+	// ArbStatePlanned is an arb sized and priced with nothing sent yet
+	ArbStatePlanned ArbState = "planned"
+	// ArbStateSkipped is an opportunity journaled without being traded, see ArbRecord.SkipReason
+	ArbStateSkipped ArbState = "skipped"
+	// ArbStateCanceled is an arb dropped before its swap was broadcasted
+	ArbStateCanceled ArbState = "canceled"
+	// ArbStateOsmosisSubmitted is an arb whose swap is signed and may have been broadcasted
+	ArbStateOsmosisSubmitted ArbState = "osmosis_submitted"
+	// ArbStateOsmosisFailed is an arb whose swap was not executed, leaving nothing to hedge
+	ArbStateOsmosisFailed ArbState = "osmosis_failed"
+	// ArbStateOsmosisConfirmed is an arb whose swap executed and is waiting for its hedge
+	ArbStateOsmosisConfirmed ArbState = "osmosis_confirmed"
+	// ArbStateHedgeSubmitted is an arb with a CEX order that may not be settled yet
+	ArbStateHedgeSubmitted ArbState = "hedge_submitted"
+	// ArbStateHedgeFilled is an arb whose hedge filled, the arb is done
+	ArbStateHedgeFilled ArbState = "hedge_filled"
+	// ArbStateHedgeFailed is an arb whose hedge gave up, the unhedged part is to be swapped back
+	ArbStateHedgeFailed ArbState = "hedge_failed"
+	// ArbStateUnwindSubmitted is an arb whose unwind swap is signed and may have been broadcasted
+	ArbStateUnwindSubmitted ArbState = "unwind_submitted"
+	// ArbStateUnwound is an arb whose unhedged part was swapped back on Osmosis
+	ArbStateUnwound ArbState = "unwound"
+	// ArbStateUnwindFailed is an arb whose unwind gave up, its unhedged part is left as is
+	ArbStateUnwindFailed ArbState = "unwind_failed"
+)
+
+// InFlight returns whether an arb in state s still has a leg to settle
+func (s ArbState) InFlight() bool {
+	switch s {
+	case ArbStatePlanned, ArbStateOsmosisSubmitted, ArbStateOsmosisConfirmed,
+		ArbStateHedgeSubmitted, ArbStateHedgeFailed, ArbStateUnwindSubmitted:
+		return true
+	}
+	return false
+}
+
+// HedgeProgress is the exact state of an arb's CEX leg, kept to resume it after
+// a restart. Quantities are in human readable units of the base asset.
+type HedgeProgress struct {
+	Side     OrderSide `json:"side"`
+	Quantity sdk.Dec   `json:"quantity"`
+	// LimitPrice is the worst price the hedge may fill at, zero for market orders
+	LimitPrice sdk.Dec `json:"limit_price"`
+
+	Filled sdk.Dec `json:"filled"`
+	// Notional is the sum of every fill's quantity times price, in quote
+	Notional    sdk.Dec            `json:"notional"`
+	Commissions map[string]sdk.Dec `json:"commissions,omitempty"`
+
+	Attempts int `json:"attempts"`
+	// ClientOrderID is the order of the last attempt until it is settled
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// arbExecution is an arb being driven through its states. Waits end early once
+// ctx is done, leaving the arb in flight to be resumed by the next run.
+type arbExecution struct {
+	ctx        context.Context
+	seedConfig SeedConfig
+	venue      CEXVenue
+	journal    *Journal
+	pair       TradingPair
+	record     *ArbRecord
+}
+
+// setState moves the arb to state and journals it. Arbs reaching a final state
+// get their realized PnL.
+func (e *arbExecution) setState(state ArbState) error {
+	e.record.State = state
+	arbStates.WithLabelValues(e.pair.Name, string(state)).Inc()
+	if !state.InFlight() {
+		e.record.CalculateRealizedPnL(cexCommissionInQuote(e.venue, e.pair, *e.record))
+	}
+	return e.persist()
+}
+
+// transition is setState for steps that go ahead even if the journal can't be written
+func (e *arbExecution) transition(state ArbState) {
+	if err := e.setState(state); err != nil {
+		fmt.Println("Error journaling arb", e.record.ID, "as", state, ":", err)
+	}
+}
+
+func (e *arbExecution) persist() error {
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.Record(*e.record)
+}
+
+// ResumeArbs drives the arbs the journal still has in flight, left behind by a
+// restart or by an unwind that failed, towards a final state. It returns the
+// pairs that still have an arb in flight, which should not be traded meanwhile.
+func ResumeArbs(ctx context.Context, seedConfig SeedConfig, venue CEXVenue, arbConfig ArbConfig, journal *Journal) (map[string]bool, error) {
+	if journal == nil {
+		return nil, nil
+	}
+
+	records, err := journal.InFlight()
+	if err != nil {
+		return nil, fmt.Errorf("error reading arbs in flight: %v", err)
+	}
+
+	inFlight := make(map[string]bool)
+	var errs []error
+	for i := range records {
+		record := &records[i]
+		pair, ok := arbConfig.Pair(record.Pair)
+		if !ok {
+			errs = append(errs, fmt.Errorf("arb %s is in flight on %s, which is not configured", record.ID, record.Pair))
+			continue
+		}
+
+		fmt.Println("Resuming", pair.Name, "arb", record.ID, "from", record.State)
+		e := &arbExecution{ctx: ctx, seedConfig: seedConfig, venue: venue, journal: journal, pair: pair, record: record}
+		if err := resumeArb(e); err != nil {
+			record.Error = err.Error()
+			if err := e.persist(); err != nil {
+				fmt.Println("Error journaling arb", record.ID, ":", err)
+			}
+			errs = append(errs, fmt.Errorf("%s arb %s: %w", pair.Name, record.ID, err))
+		}
+		if record.State.InFlight() {
+			inFlight[pair.Name] = true
+		}
+	}
+
+	return inFlight, errors.Join(errs...)
+}
+
+func resumeArb(e *arbExecution) error {
+	record := e.record
+	switch record.State {
+	case ArbStatePlanned:
+		// the swap is journaled as submitted before it is broadcasted, so nothing went out
+		e.transition(ArbStateCanceled)
+		return nil
+	case ArbStateOsmosisSubmitted:
+		result, err := waitForOsmosisTx(e.ctx, e.seedConfig, record.OsmosisTxHash, record.OsmosisTimeoutHeight)
+		if err != nil {
+			return err
+		}
+		// the auction bid of a resumed swap is unknown, only its tx fees are counted
+		tokenOutDenom := e.pair.QuoteDenom
+		if record.OsmosisIn.Denom == e.pair.QuoteDenom {
+			tokenOutDenom = e.pair.BaseDenom
+		}
+		return settleOsmosisLeg(e, swapResultFromTx(result, record.OsmosisIn, tokenOutDenom, result.Fee))
+	case ArbStateUnwindSubmitted:
+		result, err := waitForOsmosisTx(e.ctx, e.seedConfig, record.UnwindTxHash, record.UnwindTimeoutHeight)
+		if err != nil {
+			return err
+		}
+		if err := recordUnwind(e, swapResultFromTx(result, record.UnwindIn, record.OsmosisIn.Denom, result.Fee)); err != nil {
+			return err
+		}
+	}
+	return advanceArb(e)
+}
+
+// waitForOsmosisTx waits for the outcome of a tx broadcasted by an earlier run
+func waitForOsmosisTx(ctx context.Context, seedConfig SeedConfig, hash string, timeoutHeight uint64) (TxResult, error) {
+	txClient := txtypes.NewServiceClient(seedConfig.GRPCConnection)
+	tm := tmservice.NewServiceClient(seedConfig.GRPCConnection)
+	return WaitForTx(ctx, txClient, tm, hash, timeoutHeight)
+}
+
+// settleOsmosisLeg records the outcome of the arb's swap and, if it executed,
+// hedges it
+func settleOsmosisLeg(e *arbExecution, result SwapResult) error {
+	record := e.record
+	recordSwapResult(e.seedConfig, e.pair, result, record)
+	record.OsmosisOut = result.TokenOut
+
+	if err := checkOsmosisLegConfirmed(result.TxResult); err != nil {
+		e.transition(ArbStateOsmosisFailed)
+		return err
+	}
+
+	// a hedge selling what the swap bought sells what it actually returned
+	if record.Hedge.Side == OrderSideSell {
+		filters, err := e.venue.GetSymbolFilters(e.pair.CEXSymbol)
+		if err != nil {
+			return fmt.Errorf("error fetching %s %s filters: %v", e.venue.Name(), e.pair.CEXSymbol, err)
+		}
+		quantity := hedgeQuantity(e.pair, filters, record.OsmosisOut.Amount)
+		if !quantity.Equal(record.Hedge.Quantity) {
+			fmt.Println("Osmosis swap of arb", record.ID, "returned", quantity, e.pair.CEXBaseAsset, "to hedge instead of", record.Hedge.Quantity)
+		}
+		record.Hedge.Quantity = quantity
+	}
+
+	if e.pair.HedgeOrderType != OrderTypeMarket {
+		price, err := hedgeLimitPrice(e.pair, record.Hedge.Side, record.OsmosisIn, record.OsmosisOut)
+		if err != nil {
+			fmt.Println("Error pricing the hedge of arb", record.ID, ", hedging at market:", err)
+		} else {
+			record.Hedge.LimitPrice = price
+		}
+	}
+
+	e.transition(ArbStateOsmosisConfirmed)
+	return advanceArb(e)
+}
+
+// advanceArb drives an arb with a confirmed swap to a final state. The hedge is
+// retried with backoff and, once it gives up, whatever it did not hedge is
+// swapped back on Osmosis.
+func advanceArb(e *arbExecution) error {
+	for {
+		var err error
+		switch e.record.State {
+		case ArbStateOsmosisConfirmed, ArbStateHedgeSubmitted:
+			err = hedgeArb(e)
+		case ArbStateHedgeFailed:
+			err = unwindArb(e)
+		case ArbStateUnwound:
+			hedge := e.record.Hedge
+			return fmt.Errorf("%s hedge filled %s of %s after %d attempts, the rest was swapped back on Osmosis",
+				e.venue.Name(), hedge.Filled, hedge.Quantity, hedge.Attempts)
+		case ArbStateUnwindFailed:
+			hedge := e.record.Hedge
+			return fmt.Errorf("%s hedge filled %s of %s after %d attempts, the rest could not be swapped back after %d attempts",
+				e.venue.Name(), hedge.Filled, hedge.Quantity, hedge.Attempts, e.record.UnwindAttempts)
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// hedgeArb sends CEX orders for what the arb has left to hedge, until it is
+// hedged or the pair's attempts run out
+func hedgeArb(e *arbExecution) error {
+	pair, record := e.pair, e.record
+	hedge := &record.Hedge
+
+	filters, err := e.venue.GetSymbolFilters(pair.CEXSymbol)
+	if err != nil {
+		return fmt.Errorf("error fetching %s %s filters: %v", e.venue.Name(), pair.CEXSymbol, err)
+	}
+
+	// an order of an earlier attempt, possibly placed by an interrupted run, is settled first
+	if hedge.ClientOrderID != "" {
+		if err := settleHedgeOrder(e, hedge.ClientOrderID, nil); err != nil {
+			return err
+		}
+	}
+
+	for {
+		hedgeFilters := filters.ForOrderType(pair.HedgeOrderType)
+		remaining := hedgeFilters.RoundQuantity(hedge.Quantity.Sub(hedge.Filled))
+		if hedgeFilters.BelowMinimum(remaining, hedgeReferencePrice(*record)) {
+			if remaining.IsPositive() {
+				fmt.Println("Leaving", remaining, pair.CEXBaseAsset, "of arb", record.ID, "unhedged, below what", e.venue.Name(), "accepts")
+			}
+			e.transition(ArbStateHedgeFilled)
+			return nil
+		}
+
+		if hedge.Attempts >= pair.HedgeMaxAttempts {
+			fmt.Println(e.venue.Name(), "hedge of arb", record.ID, "gave up after", hedge.Attempts, "attempts with", remaining, pair.CEXBaseAsset, "left")
+			e.transition(ArbStateHedgeFailed)
+			return nil
+		}
+		if hedge.Attempts > 0 {
+			if err := sleepCtx(e.ctx, pair.HedgeRetryBackoff(hedge.Attempts)); err != nil {
+				return fmt.Errorf("hedge of arb %s interrupted with %s %s left: %v", record.ID, remaining, pair.CEXBaseAsset, err)
+			}
+		}
+
+		hedge.Attempts++
+		request, err := hedgeRequest(e.venue, pair, filters, *hedge, remaining)
+		if err != nil {
+			fmt.Println("Error building hedge order", hedge.Attempts, "of arb", record.ID, ":", err)
+			continue
+		}
+		request.ClientOrderID = fmt.Sprintf("arb%d-%d", record.Time.UnixNano(), hedge.Attempts)
+		hedge.ClientOrderID = request.ClientOrderID
+		record.CEXOrderType = request.Type
+		if !request.Price.IsNil() {
+			record.CEXLimitPrice = decToFloat(request.Price)
+		}
+
+		// an order that could not be journaled would not be found again after a restart
+		if err := e.setState(ArbStateHedgeSubmitted); err != nil {
+			return fmt.Errorf("error journaling hedge order %s: %v", request.ClientOrderID, err)
+		}
+
+		order, err := e.venue.PlaceOrder(request)
+		if err != nil {
+			// the order may have reached the venue anyway, settling looks it up
+			fmt.Println("Error placing", e.venue.Name(), "hedge order", request.ClientOrderID, ":", err)
+			order = OrderResult{}
+		}
+		if err == nil && order.Status != OrderStatusNew && order.Status != OrderStatusPartiallyFilled {
+			applyHedgeFill(e, order)
+			continue
+		}
+		if err := settleHedgeOrder(e, request.ClientOrderID, order.Commissions); err != nil {
+			return err
+		}
+	}
+}
+
+// settleHedgeOrder waits for a hedge order to stop executing, cancelling it if it
+// still rests on the book after the pair's retry backoff, and adds its fills to
+// the hedge. commissions are the ones reported when the order was placed, if any.
+// Venues that push order updates end the wait as soon as the order fills, and
+// the order is cancelled right away once the arb's ctx is done.
+func settleHedgeOrder(e *arbExecution, clientOrderID string, commissions map[string]sdk.Dec) error {
+	venue, symbol := e.venue, e.pair.CEXSymbol
+	hedge := &e.record.Hedge
+
+	order, err := venue.GetOrderStatus(symbol, clientOrderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		fmt.Println(venue.Name(), "has no hedge order", clientOrderID, ", it was never placed")
+		hedge.ClientOrderID = ""
+		return e.persist()
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching %s hedge order %s: %v", venue.Name(), clientOrderID, err)
+	}
+
+	resting := func(order OrderResult) bool {
+		return order.Status == OrderStatusNew || order.Status == OrderStatusPartiallyFilled
+	}
+	if resting(order) {
+		ctx, cancel := context.WithTimeout(e.ctx, e.pair.HedgeRetryBackoff(hedge.Attempts))
+		if watcher, ok := venue.(OrderWatcher); ok {
+			if streamed, ok := watcher.WaitForOrder(ctx, symbol, clientOrderID); ok {
+				order = streamed
+			}
+		}
+		if resting(order) {
+			<-ctx.Done()
+		}
+		cancel()
+	}
+	if resting(order) {
+		if err := venue.CancelOrder(symbol, clientOrderID); err != nil && !errors.Is(err, ErrOrderNotFound) {
+			return fmt.Errorf("error cancelling %s hedge order %s: %v", venue.Name(), clientOrderID, err)
+		}
+		// the order may have filled before the cancel went through
+		order, err = venue.GetOrderStatus(symbol, clientOrderID)
+		if err != nil {
+			return fmt.Errorf("error fetching %s hedge order %s: %v", venue.Name(), clientOrderID, err)
+		}
+		if resting(order) {
+			return fmt.Errorf("%s hedge order %s is still %s after cancelling it", venue.Name(), clientOrderID, order.Status)
+		}
+	}
+
+	if len(order.Commissions) == 0 {
+		order.Commissions = commissions
+	}
+	applyHedgeFill(e, order)
+	return nil
+}
+
+// applyHedgeFill adds the fills of a settled hedge order to the arb
+func applyHedgeFill(e *arbExecution, order OrderResult) {
+	record := e.record
+	hedge := &record.Hedge
+
+	if order.ExecutedQuantity.IsPositive() {
+		hedge.Filled = hedge.Filled.Add(order.ExecutedQuantity)
+		hedge.Notional = hedge.Notional.Add(order.ExecutedQuantity.Mul(order.Price))
+	}
+	if hedge.Commissions == nil {
+		hedge.Commissions = make(map[string]sdk.Dec)
+	}
+	for asset, commission := range order.Commissions {
+		if total, ok := hedge.Commissions[asset]; ok {
+			commission = commission.Add(total)
+		}
+		hedge.Commissions[asset] = commission
+	}
+	hedge.ClientOrderID = ""
+
+	record.CEXOrderID = order.OrderID
+	record.CEXOrderStatus = order.Status
+	record.CEXFilledQuantity = decToFloat(hedge.Filled)
+	if hedge.Filled.IsPositive() {
+		record.CEXFillPrice = decToFloat(hedge.Notional.Quo(hedge.Filled))
+	}
+	record.CEXCommissions = make(map[string]float64, len(hedge.Commissions))
+	for asset, commission := range hedge.Commissions {
+		record.CEXCommissions[asset] = decToFloat(commission)
+	}
+
+	fmt.Println(e.venue.Name(), "hedge order", order.OrderID, "is", order.Status, ", hedged", hedge.Filled, "of", hedge.Quantity)
+	if err := e.persist(); err != nil {
+		fmt.Println("Error journaling arb", record.ID, ":", err)
+	}
+}
+
+// hedgeReferencePrice is the price the CEX filters are checked at for what is
+// left to hedge
+func hedgeReferencePrice(record ArbRecord) sdk.Dec {
+	if record.Hedge.LimitPrice.IsPositive() {
+		return record.Hedge.LimitPrice
+	}
+	if record.Hedge.Side == OrderSideBuy {
+		return floatToDec(record.CEXBuyPrice)
+	}
+	return floatToDec(record.CEXSellPrice)
+}
+
+// hedgeRequest builds the CEX order for quantity of the hedge. Limit orders are
+// priced at the hedge's limit price, maker orders are pulled back behind the
+// touch so they don't cross.
+func hedgeRequest(venue CEXVenue, pair TradingPair, filters SymbolFilters, hedge HedgeProgress, quantity sdk.Dec) (OrderRequest, error) {
+	request := OrderRequest{Symbol: pair.CEXSymbol, Side: hedge.Side, Type: pair.HedgeOrderType, Quantity: quantity}
+	if request.Type == OrderTypeMarket {
+		return request, nil
+	}
+	if !hedge.LimitPrice.IsPositive() {
+		request.Type = OrderTypeMarket
+		return request, nil
+	}
+
+	price := hedge.LimitPrice
+	if request.Type == OrderTypeLimitMaker {
+		book, err := venue.GetDepth(pair.CEXSymbol, 1)
+		if err != nil {
+			return OrderRequest{}, fmt.Errorf("error fetching %s %s order book: %v", venue.Name(), pair.CEXSymbol, err)
+		}
+		price = makerPrice(book, hedge.Side, price, filters.TickSize)
+	}
+
+	request.Price = filters.RoundPrice(hedge.Side, price)
+	return request, nil
+}
+
+// hedgeLimitPrice returns the price, in quote per base, the Osmosis swap of
+// tokenIn for tokenOut executed at, the worst the hedge can fill at without a loss
+func hedgeLimitPrice(pair TradingPair, side OrderSide, tokenIn, tokenOut sdk.Coin) (sdk.Dec, error) {
+	if !tokenIn.Amount.IsPositive() || !tokenOut.Amount.IsPositive() {
+		return sdk.Dec{}, fmt.Errorf("osmosis swap of %s returned %s", tokenIn, tokenOut)
+	}
+
+	switch side {
+	case OrderSideBuy:
+		quoteOut := fromBaseUnits(tokenOut.Amount, pair.QuoteExponent)
+		return quoteOut.QuoTruncate(fromBaseUnits(tokenIn.Amount, pair.BaseExponent)), nil
+	case OrderSideSell:
+		quoteIn := fromBaseUnits(tokenIn.Amount, pair.QuoteExponent)
+		return quoteIn.QuoRoundUp(fromBaseUnits(tokenOut.Amount, pair.BaseExponent)), nil
+	}
+	return sdk.Dec{}, fmt.Errorf("invalid order side %s", side)
+}
+
+// unwindArb swaps the share of what the Osmosis leg returned that the hedge did
+// not cover back into the denom the leg spent, at a fresh quote less the pair's
+// slippage tolerance. The swap may return at most the pair's max unwind loss
+// less than the Osmosis leg's own price, and is tried up to the pair's unwind
+// attempts before the arb is left unwind_failed.
+func unwindArb(e *arbExecution) error {
+	record, pair := e.record, e.pair
+	hedge := record.Hedge
+
+	if record.UnwindAttempts >= pair.UnwindMaxAttempts {
+		fmt.Println("Unwind of arb", record.ID, "gave up after", record.UnwindAttempts, "attempts")
+		e.transition(ArbStateUnwindFailed)
+		return nil
+	}
+	// the attempt is journaled first, so attempts failing before the swap goes out count too
+	record.UnwindAttempts++
+	if err := e.persist(); err != nil {
+		return fmt.Errorf("error journaling unwind attempt %d: %v", record.UnwindAttempts, err)
+	}
+
+	amountIn := sdk.ZeroInt()
+	switch {
+	case hedge.Side == OrderSideSell:
+		// the swap bought base, whatever of it the hedge did not sell goes back
+		amountIn = record.OsmosisOut.Amount.Sub(toBaseUnits(hedge.Filled, pair.BaseExponent))
+	case hedge.Quantity.IsPositive():
+		// the swap sold base, the quote it returned for what the hedge did not buy back goes back
+		unhedged := hedge.Quantity.Sub(hedge.Filled)
+		amountIn = sdk.NewDecFromInt(record.OsmosisOut.Amount).Mul(unhedged).Quo(hedge.Quantity).TruncateInt()
+	}
+	if !amountIn.IsPositive() {
+		return fmt.Errorf("nothing of %s left to unwind", record.OsmosisOut)
+	}
+
+	quote, err := getOsmosisPriceAndRoute(e.seedConfig, record.OsmosisOut.Denom, record.OsmosisIn.Denom, amountIn)
+	if err != nil {
+		return fmt.Errorf("error quoting the unwind of %s%s: %v", amountIn, record.OsmosisOut.Denom, err)
+	}
+	lossCap := unwindLossCap(record.OsmosisIn, record.OsmosisOut, amountIn, pair.MaxUnwindLossBps)
+	tokenOutMinAmount, err := calculateTokenOutMinAmount(quote.TokenOutAmount, pair.MaxSlippageBps, lossCap)
+	if err != nil {
+		return fmt.Errorf("unwind of arb %s is beyond the max unwind loss of %d bps: %v", record.ID, pair.MaxUnwindLossBps, err)
+	}
+
+	fmt.Println("Unwinding arb", record.ID, ": swapping", amountIn, record.OsmosisOut.Denom, "back for at least", tokenOutMinAmount, record.OsmosisIn.Denom)
+	record.UnwindIn = sdk.NewCoin(record.OsmosisOut.Denom, routeTokenInAmount(quote.Route))
+	result, err := e.seedConfig.osmosis().Swap(quote.Route, record.OsmosisOut.Denom, tokenOutMinAmount, sdk.Coin{}, func(hash string, timeoutHeight uint64) error {
+		record.UnwindTxHash = hash
+		record.UnwindTimeoutHeight = timeoutHeight
+		return e.setState(ArbStateUnwindSubmitted)
+	})
+	if err != nil {
+		return fmt.Errorf("error unwinding arb %s: %v", record.ID, err)
+	}
+	return recordUnwind(e, result)
+}
+
+// unwindLossCap returns the least amountIn of the Osmosis leg's tokenOut may be
+// swapped back for, the leg's own price less maxLossBps
+func unwindLossCap(tokenIn, tokenOut sdk.Coin, amountIn sdk.Int, maxLossBps uint64) sdk.Int {
+	if !tokenOut.Amount.IsPositive() || maxLossBps >= 10000 {
+		return sdk.ZeroInt()
+	}
+	return sdk.NewDecFromInt(tokenIn.Amount).
+		MulInt(amountIn).
+		QuoInt(tokenOut.Amount).
+		MulInt64(int64(10000 - maxLossBps)).
+		QuoInt64(10000).
+		Ceil().
+		TruncateInt()
+}
+
+// sleepCtx waits for d, returning ctx's error if it is done first
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// recordUnwind records the outcome of the unwind swap. A swap that did not
+// execute leaves the arb to be unwound again.
+func recordUnwind(e *arbExecution, result SwapResult) error {
+	record, pair := e.record, e.pair
+	record.UnwindTxHash = result.TxHash
+	record.UnwindTxStatus = result.Status
+
+	for _, coin := range result.Fees {
+		value, err := convertDenomToQuote(e.seedConfig, pair, coin)
+		if err != nil {
+			fmt.Println("Error valuing", coin, "in", pair.CEXQuoteAsset, ":", err)
+			continue
+		}
+		record.OsmosisCosts += decToFloat(value)
+	}
+
+	if err := checkOsmosisLegConfirmed(result.TxResult); err != nil {
+		e.transition(ArbStateHedgeFailed)
+		return fmt.Errorf("unwind: %v", err)
+	}
+
+	exponent := func(denom string) int {
+		if denom == pair.BaseDenom {
+			return pair.BaseExponent
+		}
+		return pair.QuoteExponent
+	}
+	record.UnwindIn = result.TokenIn
+	record.UnwindOut = result.TokenOut
+	record.UnwindTokenIn = decToFloat(fromBaseUnits(result.TokenIn.Amount, exponent(result.TokenIn.Denom)))
+	record.UnwindTokenOut = decToFloat(fromBaseUnits(result.TokenOut.Amount, exponent(result.TokenOut.Denom)))
+
+	e.transition(ArbStateUnwound)
+	return nil
+}
@@ -0,0 +1,170 @@
+package src
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
+)
+
+func testArbPair() TradingPair {
+	pair := DefaultBTCUSDCPair
+	pair.BaseExponent = 8
+	pair.QuoteExponent = 6
+	return pair
+}
+
+// testArbVenues returns Binance at 59990/60010 and Osmosis at osmosisPrice, in
+// USDC per BTC, less 20 bps of pool fees
+func testArbVenues(pair TradingPair, osmosisPrice int64) (*FakeVenue, *FakeOsmosis) {
+	venue := NewFakeVenue()
+	venue.SetMarket(pair.CEXSymbol, pair.CEXBaseAsset, pair.CEXQuoteAsset, sdk.NewDec(60000))
+	venue.SetOrderBook(pair.CEXSymbol, OrderBook{
+		Bids: []PriceLevel{{Price: sdk.NewDec(59990), Quantity: sdk.NewDec(10)}},
+		Asks: []PriceLevel{{Price: sdk.NewDec(60010), Quantity: sdk.NewDec(10)}},
+	})
+	venue.SetTradingFees(TradingFees{Maker: sdk.MustNewDecFromStr("0.001"), Taker: sdk.MustNewDecFromStr("0.001")})
+	venue.SetBalance(pair.CEXBaseAsset, sdk.NewDec(1))
+	venue.SetBalance(pair.CEXQuoteAsset, sdk.NewDec(100_000))
+
+	osmosis := NewFakeOsmosis()
+	// smallest units of quote per smallest unit of base
+	osmosis.SetPrice(pair.BaseDenom, pair.QuoteDenom, sdk.NewDec(osmosisPrice).QuoInt64(100))
+	osmosis.SetFeeRate(sdk.MustNewDecFromStr("0.002"))
+	osmosis.SetBalance(pair.BaseDenom, sdk.NewInt(100_000_000))
+	osmosis.SetBalance(pair.QuoteDenom, sdk.NewInt(100_000_000_000))
+	osmosis.SetSwapFee(sdk.NewCoins(sdk.NewInt64Coin(pair.QuoteDenom, 10_000)))
+	osmosis.SetAuctionParams(auctiontypes.Params{
+		ReserveFee:      sdk.NewInt64Coin(pair.QuoteDenom, 1_000_000),
+		MinBidIncrement: sdk.NewInt64Coin(pair.QuoteDenom, 1_000_000),
+	})
+	return venue, osmosis
+}
+
+func TestCheckArbitrage(t *testing.T) {
+	tests := []struct {
+		name         string
+		osmosisPrice int64
+		minProfit    float64
+		wantState    ArbState
+		wantSwapIn   string
+		wantCEXBase  sdk.Dec
+		wantCEXQuote sdk.Dec
+	}{
+		{
+			name:         "osmosis above binance",
+			osmosisPrice: 61_000,
+			wantState:    ArbStateHedgeFilled,
+			wantSwapIn:   DefaultBTCUSDCPair.BaseDenom,
+			// bought 0.1 BTC at 60010, paying 0.1% of it in BTC
+			wantCEXBase:  sdk.MustNewDecFromStr("1.0999"),
+			wantCEXQuote: sdk.NewDec(93_999),
+		},
+		{
+			name:         "osmosis below binance",
+			osmosisPrice: 59_000,
+			wantState:    ArbStateHedgeFilled,
+			wantSwapIn:   DefaultBTCUSDCPair.QuoteDenom,
+			// spent 6000 USDC on Osmosis for 0.10149152 BTC, sold at 59990 paying 0.1% of it in USDT
+			wantCEXBase:  sdk.MustNewDecFromStr("0.89850848"),
+			wantCEXQuote: sdk.MustNewDecFromStr("106082.3878085152"),
+		},
+		{
+			name:         "no spread",
+			osmosisPrice: 60_000,
+			wantCEXBase:  sdk.NewDec(1),
+			wantCEXQuote: sdk.NewDec(100_000),
+		},
+		{
+			name:         "below min profit",
+			osmosisPrice: 61_000,
+			minProfit:    1_000,
+			wantState:    ArbStateSkipped,
+			wantCEXBase:  sdk.NewDec(1),
+			wantCEXQuote: sdk.NewDec(100_000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair := testArbPair()
+			pair.MinProfit = tt.minProfit
+			venue, osmosis := testArbVenues(pair, tt.osmosisPrice)
+
+			journal, err := OpenJournal(filepath.Join(t.TempDir(), "journal"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer journal.Close()
+
+			seedConfig := SeedConfig{Osmosis: osmosis}
+			arbConfig := ArbConfig{Pairs: []TradingPair{pair}}
+			if err := CheckArbitrage(context.Background(), seedConfig, venue, arbConfig, journal); err != nil {
+				t.Fatalf("CheckArbitrage() = %v", err)
+			}
+
+			records, err := journal.Records(time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			swaps := osmosis.Swaps()
+			if tt.wantState == "" {
+				if len(records) != 0 || len(swaps) != 0 {
+					t.Fatalf("journaled %d arbs and sent %d swaps, want none", len(records), len(swaps))
+				}
+			} else {
+				if len(records) != 1 {
+					t.Fatalf("journaled %d arbs, want 1", len(records))
+				}
+				if state := records[0].State; state != tt.wantState {
+					t.Errorf("arb state = %s, want %s", state, tt.wantState)
+				}
+			}
+
+			if tt.wantSwapIn != "" {
+				if len(swaps) != 1 {
+					t.Fatalf("sent %d swaps, want 1", len(swaps))
+				}
+				swap := swaps[0]
+				if swap.TokenIn.Denom != tt.wantSwapIn {
+					t.Errorf("swapped %s, want %s in", swap.TokenIn, tt.wantSwapIn)
+				}
+				reserveFee := sdk.NewInt(1_000_000)
+				if bid := swap.AuctionBid.Amount; bid.LT(reserveFee) || !bid.Sub(reserveFee).ModRaw(1_000_000).IsZero() {
+					t.Errorf("auction bid %s is not the reserve fee plus whole increments", swap.AuctionBid)
+				}
+				if records[0].RealizedPnL <= 0 {
+					t.Errorf("realized PnL = %f, want a profit", records[0].RealizedPnL)
+				}
+			} else if len(swaps) != 0 {
+				t.Errorf("sent %d swaps, want none", len(swaps))
+			}
+
+			balances, err := venue.GetBalances([]string{pair.CEXBaseAsset, pair.CEXQuoteAsset})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if base := balances[pair.CEXBaseAsset].Free; !base.Equal(tt.wantCEXBase) {
+				t.Errorf("%s balance = %s, want %s", pair.CEXBaseAsset, base, tt.wantCEXBase)
+			}
+			if quote := balances[pair.CEXQuoteAsset].Free; !quote.Equal(tt.wantCEXQuote) {
+				t.Errorf("%s balance = %s, want %s", pair.CEXQuoteAsset, quote, tt.wantCEXQuote)
+			}
+
+			// a filled hedge trades on Binance the base the swap traded on Osmosis,
+			// the commission Binance takes in base aside
+			if tt.wantState == ArbStateHedgeFilled {
+				cexBaseDelta := balances[pair.CEXBaseAsset].Free.Sub(sdk.NewDec(1))
+				if commission, ok := records[0].Hedge.Commissions[pair.CEXBaseAsset]; ok {
+					cexBaseDelta = cexBaseDelta.Add(commission)
+				}
+				osmosisBaseDelta := fromBaseUnits(osmosis.Balance(pair.BaseDenom).SubRaw(100_000_000), pair.BaseExponent)
+				if net := cexBaseDelta.Add(osmosisBaseDelta); net.Abs().GT(sdk.NewDecWithPrec(1, int64(pair.BaseExponent))) {
+					t.Errorf("%s moved %s on Binance and %s on Osmosis, want them to cancel out", pair.CEXBaseAsset, cexBaseDelta, osmosisBaseDelta)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// osmosisVenue is the venue name Osmosis balances are kept under
+const osmosisVenue = "Osmosis"
+
+// Balance is a venue's balance of an asset in human readable units. Locked is
+// held by open orders and can't be traded until they close.
+type Balance struct {
+	Free   sdk.Dec `json:"free"`
+	Locked sdk.Dec `json:"locked"`
+}
+
+// NewBalance returns a balance of free and locked, a nil amount counts as zero
+func NewBalance(free, locked sdk.Dec) Balance {
+	if free.IsNil() {
+		free = sdk.ZeroDec()
+	}
+	if locked.IsNil() {
+		locked = sdk.ZeroDec()
+	}
+	return Balance{Free: free, Locked: locked}
+}
+
+// Total returns the free and locked balance
+func (b Balance) Total() sdk.Dec {
+	b = NewBalance(b.Free, b.Locked)
+	return b.Free.Add(b.Locked)
+}
+
+func (b Balance) String() string {
+	b = NewBalance(b.Free, b.Locked)
+	if b.Locked.IsZero() {
+		return b.Free.String()
+	}
+	return fmt.Sprintf("%s (%s locked)", b.Free, b.Locked)
+}
+
+// Balances holds balances by venue and asset. Assets are keyed by their CEX
+// name on every venue so they can be summed across venues.
+type Balances map[string]map[string]Balance
+
+// Set sets venue's balance of asset
+func (b Balances) Set(venue, asset string, balance Balance) {
+	if b[venue] == nil {
+		b[venue] = make(map[string]Balance)
+	}
+	b[venue][asset] = NewBalance(balance.Free, balance.Locked)
+}
+
+// Get returns venue's balance of asset, and whether it is known
+func (b Balances) Get(venue, asset string) (Balance, bool) {
+	balance, ok := b[venue][asset]
+	return balance, ok
+}
+
+// Free returns the free balance of asset summed over all venues
+func (b Balances) Free(asset string) sdk.Dec {
+	free := sdk.ZeroDec()
+	for _, assets := range b {
+		if balance, ok := assets[asset]; ok {
+			free = free.Add(balance.Free)
+		}
+	}
+	return free
+}
+
+// FreeOn returns venue's free balance of asset, zero when it is not known
+func (b Balances) FreeOn(venue, asset string) sdk.Dec {
+	balance, _ := b.Get(venue, asset)
+	return NewBalance(balance.Free, balance.Locked).Free
+}
+
+// Require returns an error naming the first of assets missing on a venue
+func (b Balances) Require(venues []string, assets ...string) error {
+	for _, venue := range venues {
+		for _, asset := range assets {
+			if _, ok := b.Get(venue, asset); !ok {
+				return fmt.Errorf("no %s balance on %s", asset, venue)
+			}
+		}
+	}
+	return nil
+}
+
+// Merge copies the balances of other into b
+func (b Balances) Merge(other Balances) {
+	for venue, assets := range other {
+		for asset, balance := range assets {
+			b.Set(venue, asset, balance)
+		}
+	}
+}
+
+// String lists the balances sorted by venue and asset
+func (b Balances) String() string {
+	var lines []string
+	for venue, assets := range b {
+		for asset, balance := range assets {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", venue, asset, balance))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, ", ")
+}
+
+// observe exports the balances as metrics
+func (b Balances) observe() {
+	for venue, assets := range b {
+		for asset, balance := range assets {
+			balances.WithLabelValues(venue, asset).Set(decToFloat(balance.Free))
+			lockedBalances.WithLabelValues(venue, asset).Set(decToFloat(balance.Locked))
+		}
+	}
+}
+
+// GetPairBalances returns the balances of the pair's base and quote asset on
+// venue and on Osmosis. It fails if either venue is missing either asset.
+func GetPairBalances(seedConfig SeedConfig, venue CEXVenue, pair TradingPair) (Balances, error) {
+	assets := []string{pair.CEXBaseAsset, pair.CEXQuoteAsset}
+
+	cexBalances, err := venue.GetBalances(assets)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s balance: %v", venue.Name(), err)
+	}
+
+	osmosisBalances, err := seedConfig.osmosis().PairBalance(pair)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Osmosis balance: %v", err)
+	}
+
+	balances := make(Balances)
+	for asset, balance := range cexBalances {
+		balances.Set(venue.Name(), asset, balance)
+	}
+	balances.Merge(osmosisBalances)
+
+	if err := balances.Require([]string{venue.Name(), osmosisVenue}, assets...); err != nil {
+		return nil, err
+	}
+	balances.observe()
+	return balances, nil
+}
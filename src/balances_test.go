@@ -0,0 +1,120 @@
+package src
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func testBalances() Balances {
+	balances := make(Balances)
+	balances.Set("Binance", "USDT", NewBalance(sdk.NewDec(100), sdk.NewDec(5)))
+	balances.Set("Binance", "BTC", NewBalance(sdk.MustNewDecFromStr("0.5"), sdk.Dec{}))
+	balances.Set(osmosisVenue, "BTC", NewBalance(sdk.NewDec(1), sdk.Dec{}))
+	return balances
+}
+
+func TestBalancesMissingAsset(t *testing.T) {
+	balances := testBalances()
+
+	tests := []struct {
+		name      string
+		venue     string
+		asset     string
+		wantKnown bool
+		wantFree  sdk.Dec
+	}{
+		{name: "known", venue: "Binance", asset: "USDT", wantKnown: true, wantFree: sdk.NewDec(100)},
+		{name: "asset missing from venue", venue: osmosisVenue, asset: "USDT", wantFree: sdk.ZeroDec()},
+		{name: "unknown venue", venue: "Kraken", asset: "BTC", wantFree: sdk.ZeroDec()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := balances.Get(tt.venue, tt.asset); ok != tt.wantKnown {
+				t.Errorf("Get(%s, %s) known = %v, want %v", tt.venue, tt.asset, ok, tt.wantKnown)
+			}
+			if free := balances.FreeOn(tt.venue, tt.asset); !free.Equal(tt.wantFree) {
+				t.Errorf("FreeOn(%s, %s) = %s, want %s", tt.venue, tt.asset, free, tt.wantFree)
+			}
+		})
+	}
+
+	if free := balances.Free("BTC"); !free.Equal(sdk.MustNewDecFromStr("1.5")) {
+		t.Errorf("Free(BTC) = %s, want 1.5", free)
+	}
+	if free := balances.Free("ETH"); !free.IsZero() {
+		t.Errorf("Free(ETH) = %s, want 0", free)
+	}
+}
+
+func TestBalancesRequire(t *testing.T) {
+	balances := testBalances()
+
+	tests := []struct {
+		name    string
+		venues  []string
+		assets  []string
+		wantErr string
+	}{
+		{name: "all known", venues: []string{"Binance"}, assets: []string{"BTC", "USDT"}},
+		{name: "unknown venue", venues: []string{"Binance", "Kraken"}, assets: []string{"BTC"}, wantErr: "no BTC balance on Kraken"},
+		{name: "unknown asset", venues: []string{"Binance"}, assets: []string{"BTC", "ETH"}, wantErr: "no ETH balance on Binance"},
+		{name: "asset missing from one venue", venues: []string{"Binance", osmosisVenue}, assets: []string{"BTC", "USDT"}, wantErr: "no USDT balance on Osmosis"},
+		{name: "no venues", assets: []string{"ETH"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := balances.Require(tt.venues, tt.assets...)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Require() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("Require() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBalancesString(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances func() Balances
+		want     string
+	}{
+		{
+			name:     "empty",
+			balances: func() Balances { return make(Balances) },
+			want:     "",
+		},
+		{
+			name:     "sorted by venue then asset",
+			balances: testBalances,
+			want: "Binance BTC: 0.500000000000000000, " +
+				"Binance USDT: 100.000000000000000000 (5.000000000000000000 locked), " +
+				"Osmosis BTC: 1.000000000000000000",
+		},
+		{
+			name: "inserted in reverse order",
+			balances: func() Balances {
+				balances := make(Balances)
+				balances.Set(osmosisVenue, "BTC", NewBalance(sdk.NewDec(1), sdk.Dec{}))
+				balances.Set("Binance", "USDT", NewBalance(sdk.NewDec(100), sdk.NewDec(5)))
+				balances.Set("Binance", "BTC", NewBalance(sdk.MustNewDecFromStr("0.5"), sdk.Dec{}))
+				return balances
+			},
+			want: "Binance BTC: 0.500000000000000000, " +
+				"Binance USDT: 100.000000000000000000 (5.000000000000000000 locked), " +
+				"Osmosis BTC: 1.000000000000000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// map iteration order is random, repeat to catch ordering that depends on it
+			for i := 0; i < 20; i++ {
+				if got := tt.balances().String(); got != tt.want {
+					t.Fatalf("String() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
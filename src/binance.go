@@ -3,12 +3,17 @@ package src
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
 type BinanceResponse struct {
@@ -16,124 +21,545 @@ type BinanceResponse struct {
 	Price  string `json:"price"`
 }
 
-func GetBinanceBTCToUSDTPrice() (float64, error) {
-	url := fmt.Sprintf("https://api.binance.com/api/v3/ticker/price?symbol=%s", binanceBTCUSDTTicker)
-	resp, err := http.Get(url)
+// BinanceVenue implements CEXVenue against the Binance spot API
+type BinanceVenue struct {
+	client *binance.Client
+
+	// filters caches the exchangeInfo filters of each symbol traded
+	filtersMu sync.Mutex
+	filters   map[string]cachedSymbolFilters
+
+	// fees caches the account's commission rates, they are the same for every symbol
+	feesMu        sync.Mutex
+	fees          TradingFees
+	feesFetchedAt time.Time
+
+	// market keeps the order books of the watched symbols, see WatchBookTicker
+	market *binanceMarketData
+
+	// account follows the balances and orders of the account, see WatchAccount
+	account *binanceAccount
+}
+
+type cachedSymbolFilters struct {
+	filters   SymbolFilters
+	fetchedAt time.Time
+}
+
+var (
+	_ CEXVenue          = (*BinanceVenue)(nil)
+	_ BookTickerWatcher = (*BinanceVenue)(nil)
+	_ AccountWatcher    = (*BinanceVenue)(nil)
+	_ OrderWatcher      = (*BinanceVenue)(nil)
+)
+
+func NewBinanceVenue(apiKey, secretKey string) *BinanceVenue {
+	client := binance.NewClient(apiKey, secretKey)
+	// every REST call shares the one rate limit budget
+	client.HTTPClient = &http.Client{Transport: newBinanceLimiter(http.DefaultTransport)}
+	return &BinanceVenue{
+		client:  client,
+		filters: make(map[string]cachedSymbolFilters),
+		market:  newBinanceMarketData(client),
+		account: newBinanceAccount(client),
+	}
+}
+
+// NewBinanceVenueFromEnv creates a Binance venue using BINANCE_API_KEY and BINANCE_SECRET_KEY
+func NewBinanceVenueFromEnv() *BinanceVenue {
+	return NewBinanceVenue(os.Getenv("BINANCE_API_KEY"), os.Getenv("BINANCE_SECRET_KEY"))
+}
+
+func (b *BinanceVenue) Name() string {
+	return "Binance"
+}
+
+func (b *BinanceVenue) GetPrice(symbol string) (sdk.Dec, error) {
+	defer observeLatency("binance_price", time.Now())
+
+	url := fmt.Sprintf("https://api.binance.com/api/v3/ticker/price?symbol=%s", symbol)
+	resp, err := b.client.HTTPClient.Get(url)
 	if err != nil {
-		return 0, fmt.Errorf("error fetching price from Binance: %v", err)
+		return sdk.Dec{}, fmt.Errorf("error fetching price from Binance: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("error fetching price from Binance: status code %d", resp.StatusCode)
+		return sdk.Dec{}, fmt.Errorf("error fetching price from Binance: status code %d", resp.StatusCode)
 	}
 
 	var binanceResp BinanceResponse
 	err = json.NewDecoder(resp.Body).Decode(&binanceResp)
 	if err != nil {
-		return 0, fmt.Errorf("error decoding response: %v", err)
+		return sdk.Dec{}, fmt.Errorf("error decoding response: %v", err)
 	}
 
-	price, err := strconv.ParseFloat(binanceResp.Price, 64)
+	price, err := sdk.NewDecFromStr(binanceResp.Price)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing price: %v", err)
+		return sdk.Dec{}, fmt.Errorf("error parsing price: %v", err)
 	}
 
 	return price, nil
 }
 
-func GetBinanceUSDCToBTCPrice() (float64, error) {
-	btcPrice, err := GetBinanceBTCToUSDTPrice()
+// SetMarketDataStaleAfter sets how far the depth stream may lag before GetDepth
+// stops reading the streamed book and falls back to REST
+func (b *BinanceVenue) SetMarketDataStaleAfter(staleAfter time.Duration) {
+	b.market.setStaleAfter(staleAfter)
+}
+
+// GetDepth returns the book streamed for symbol while it is synced and fresh,
+// and fetches it over REST otherwise
+func (b *BinanceVenue) GetDepth(symbol string, limit int) (OrderBook, error) {
+	if book, ok := b.market.depth(symbol, limit); ok {
+		return book, nil
+	}
+
+	defer observeLatency("binance_depth", time.Now())
+
+	res, err := b.client.NewDepthService().Symbol(symbol).Limit(limit).Do(context.Background())
 	if err != nil {
-		return 0, err
+		return OrderBook{}, fmt.Errorf("error fetching depth from Binance: %v", err)
 	}
 
-	if btcPrice != 0 {
-		return 1 / btcPrice, nil
-	} else {
-		return 0, fmt.Errorf("BTC price from Binance is zero, cannot compute USDC to BTC price")
+	bids, err := parsePriceLevels(res.Bids)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	asks, err := parsePriceLevels(res.Asks)
+	if err != nil {
+		return OrderBook{}, err
 	}
+
+	return OrderBook{Bids: bids, Asks: asks}, nil
 }
 
-func GetBinanceBTCUSDTBalance() (btcBalance float64, usdtBalance float64, err error) {
-	apiKey := os.Getenv("BINANCE_API_KEY")
-	secretKey := os.Getenv("BINANCE_SECRET_KEY")
+// GetSymbolFilters returns the symbol's exchangeInfo filters, refetched once they
+// are older than binanceSymbolFiltersTTL
+func (b *BinanceVenue) GetSymbolFilters(symbol string) (SymbolFilters, error) {
+	b.filtersMu.Lock()
+	cached, ok := b.filters[symbol]
+	b.filtersMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < binanceSymbolFiltersTTL {
+		return cached.filters, nil
+	}
 
-	client := binance.NewClient(apiKey, secretKey)
-	accountService := client.NewGetAccountService()
-	res, err := accountService.Do(context.Background())
+	defer observeLatency("binance_exchange_info", time.Now())
+
+	res, err := b.client.NewExchangeInfoService().Symbol(symbol).Do(context.Background())
 	if err != nil {
-		return 0, 0, err
+		return SymbolFilters{}, fmt.Errorf("error fetching %s exchange info from Binance: %v", symbol, err)
 	}
 
-	filteredBalances := filterBalances(res.Balances, []string{"BTC", "USDT"})
-	for _, balance := range filteredBalances {
-		fmt.Printf("Asset: %s, Free: %s\n", balance.Asset, balance.Free)
+	for _, s := range res.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+
+		filters, err := parseSymbolFilters(s)
+		if err != nil {
+			return SymbolFilters{}, fmt.Errorf("error parsing %s filters: %v", symbol, err)
+		}
+
+		b.filtersMu.Lock()
+		b.filters[symbol] = cachedSymbolFilters{filters: filters, fetchedAt: time.Now()}
+		b.filtersMu.Unlock()
+		return filters, nil
+	}
+
+	return SymbolFilters{}, fmt.Errorf("binance returned no exchange info for %s", symbol)
+}
+
+// GetTradingFees returns the account's commission rates, refetched once they are
+// older than binanceTradingFeesTTL. Commissions are discounted when paying them
+// in BNB is turned on and the account holds BNB to pay them with.
+func (b *BinanceVenue) GetTradingFees(symbol string) (TradingFees, error) {
+	b.feesMu.Lock()
+	defer b.feesMu.Unlock()
+	if !b.feesFetchedAt.IsZero() && time.Since(b.feesFetchedAt) < binanceTradingFeesTTL {
+		return b.fees, nil
 	}
 
-	btcBalance, err = strconv.ParseFloat(filteredBalances[0].Free, 64)
+	defer observeLatency("binance_account", time.Now())
+
+	account, err := b.client.NewGetAccountService().Do(context.Background())
 	if err != nil {
-		return 0, 0, err
+		return TradingFees{}, fmt.Errorf("error fetching account from Binance: %v", err)
 	}
 
-	usdtBalance, err = strconv.ParseFloat(filteredBalances[1].Free, 64)
+	maker, err := sdk.NewDecFromStr(account.CommissionRates.Maker)
+	if err != nil {
+		return TradingFees{}, fmt.Errorf("invalid maker commission %q: %v", account.CommissionRates.Maker, err)
+	}
+	taker, err := sdk.NewDecFromStr(account.CommissionRates.Taker)
 	if err != nil {
-		return 0, 0, err
+		return TradingFees{}, fmt.Errorf("invalid taker commission %q: %v", account.CommissionRates.Taker, err)
+	}
+	fees := TradingFees{Maker: maker, Taker: taker, Discount: sdk.ZeroDec()}
+
+	if b.paysFeesInBNB(account.Balances) {
+		fees.Discount = sdk.MustNewDecFromStr(binanceBNBFeeDiscount)
 	}
 
-	return btcBalance, usdtBalance, nil
+	b.fees, b.feesFetchedAt = fees, time.Now()
+	return fees, nil
 }
 
-func BuyBinanceBTC(amount float64) (boughtAmount, boughtPrice float64, err error) {
-	apiKey := os.Getenv("BINANCE_API_KEY")
-	secretKey := os.Getenv("BINANCE_SECRET_KEY")
-	client := binance.NewClient(apiKey, secretKey)
+// paysFeesInBNB reports whether spot commissions are paid in BNB, which takes
+// BNB burn turned on and a BNB balance
+func (b *BinanceVenue) paysFeesInBNB(balances []binance.Balance) bool {
+	var hasBNB bool
+	for _, balance := range filterBalances(balances, []string{binanceFeeAsset}) {
+		free, err := sdk.NewDecFromStr(balance.Free)
+		hasBNB = err == nil && free.IsPositive()
+	}
+	if !hasBNB {
+		return false
+	}
+
+	burn, err := b.client.NewGetBNBBurnService().Do(context.Background())
+	if err != nil {
+		// the endpoint needs the key's margin permission, commissions are assumed undiscounted without it
+		fmt.Println("Error fetching Binance BNB burn status, assuming no fee discount:", err)
+		return false
+	}
+	return burn.SpotBNBBurn
+}
+
+// GetBalances returns the balances of assets, from the user data stream while
+// it is live and from the account endpoint otherwise
+func (b *BinanceVenue) GetBalances(assets []string) (map[string]Balance, error) {
+	if balances, ok := b.account.getBalances(assets); ok {
+		return balances, nil
+	}
+
+	defer observeLatency("binance_account", time.Now())
+
+	res, err := b.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]Balance, len(assets))
+	for _, asset := range assets {
+		balances[asset] = NewBalance(sdk.ZeroDec(), sdk.ZeroDec())
+	}
+
+	filteredBalances := filterBalances(res.Balances, assets)
+	for _, balance := range filteredBalances {
+		fmt.Printf("Asset: %s, Free: %s, Locked: %s\n", balance.Asset, balance.Free, balance.Locked)
+
+		parsed, err := parseBinanceBalance(balance.Free, balance.Locked)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s balance: %v", balance.Asset, err)
+		}
+		balances[balance.Asset] = parsed
+	}
+
+	return balances, nil
+}
+
+func (b *BinanceVenue) PlaceOrder(order OrderRequest) (OrderResult, error) {
+	defer observeLatency("binance_create_order", time.Now())
+
+	filters, err := b.GetSymbolFilters(order.Symbol)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	service := b.client.NewCreateOrderService().
+		Symbol(order.Symbol).
+		Side(binance.SideType(order.Side)).
+		Quantity(formatDec(order.Quantity, binanceQuantityDecimals)).
+		NewOrderRespType(binance.NewOrderRespTypeFULL)
+	if order.ClientOrderID != "" {
+		service = service.NewClientOrderID(order.ClientOrderID)
+	}
 
-	amountStr := strconv.FormatFloat(amount, 'f', -1, 64)
+	switch order.Type {
+	case OrderTypeMarket, "":
+		// the notional depends on where the book fills the order, the arb checks it before the Osmosis leg
+		if err := filters.CheckQuantity(order.Quantity); err != nil {
+			return OrderResult{}, fmt.Errorf("binance would reject the %s order: %v", order.Symbol, err)
+		}
+		service = service.Type(binance.OrderTypeMarket)
+	case OrderTypeLimitIOC, OrderTypeLimitMaker:
+		if order.Price.IsNil() {
+			return OrderResult{}, fmt.Errorf("%s order is missing a price", order.Type)
+		}
+		if err := filters.ForOrderType(order.Type).CheckOrder(order.Quantity, order.Price); err != nil {
+			return OrderResult{}, fmt.Errorf("binance would reject the %s order: %v", order.Symbol, err)
+		}
+		service = service.Price(formatDec(order.Price, binancePriceDecimals))
+		if order.Type == OrderTypeLimitIOC {
+			service = service.Type(binance.OrderTypeLimit).TimeInForce(binance.TimeInForceTypeIOC)
+		} else {
+			service = service.Type(binance.OrderTypeLimitMaker)
+		}
+	default:
+		return OrderResult{}, fmt.Errorf("invalid order type %s", order.Type)
+	}
 
-	// TODO: consider doing limit orders here
-	order, err := client.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).Type(binance.OrderTypeMarket).Quantity(amountStr).Do(context.Background())
+	res, err := service.Do(context.Background())
 	if err != nil {
-		return 0, 0, err
+		return OrderResult{}, err
 	}
 
-	boughtPrice, err = strconv.ParseFloat(order.Fills[0].Price, 64)
+	executedQuantity, err := sdk.NewDecFromStr(res.ExecutedQuantity)
 	if err != nil {
-		return 0, 0, err
+		return OrderResult{}, err
 	}
 
-	boughtAmount, err = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	price, commissions, err := aggregateFills(res.Fills)
 	if err != nil {
-		return 0, 0, err
+		return OrderResult{}, fmt.Errorf("error parsing binance order %d fills: %v", res.OrderID, err)
 	}
 
-	return boughtAmount, boughtPrice, nil
+	return OrderResult{
+		OrderID:          strconv.FormatInt(res.OrderID, 10),
+		ClientOrderID:    res.ClientOrderID,
+		Symbol:           res.Symbol,
+		Side:             OrderSide(res.Side),
+		Status:           OrderStatus(res.Status),
+		ExecutedQuantity: executedQuantity,
+		Price:            price,
+		Commissions:      commissions,
+	}, nil
 }
 
-func SellBinanceBTC(amount float64) (soldAmount, soldPrice float64, err error) {
-	apiKey := os.Getenv("BINANCE_API_KEY")
-	secretKey := os.Getenv("BINANCE_SECRET_KEY")
-	client := binance.NewClient(apiKey, secretKey)
+// aggregateFills returns the volume weighted average price of fills and their
+// commissions summed per asset
+func aggregateFills(fills []*binance.Fill) (sdk.Dec, map[string]sdk.Dec, error) {
+	quantity, notional := sdk.ZeroDec(), sdk.ZeroDec()
+	commissions := make(map[string]sdk.Dec)
+	for _, fill := range fills {
+		fillPrice, err := sdk.NewDecFromStr(fill.Price)
+		if err != nil {
+			return sdk.Dec{}, nil, err
+		}
+		fillQuantity, err := sdk.NewDecFromStr(fill.Quantity)
+		if err != nil {
+			return sdk.Dec{}, nil, err
+		}
+		fillCommission, err := sdk.NewDecFromStr(fill.Commission)
+		if err != nil {
+			return sdk.Dec{}, nil, err
+		}
+
+		quantity = quantity.Add(fillQuantity)
+		notional = notional.Add(fillQuantity.Mul(fillPrice))
+		if commission, ok := commissions[fill.CommissionAsset]; ok {
+			fillCommission = fillCommission.Add(commission)
+		}
+		commissions[fill.CommissionAsset] = fillCommission
+	}
+
+	if !quantity.IsPositive() {
+		return sdk.ZeroDec(), commissions, nil
+	}
+	return notional.Quo(quantity), commissions, nil
+}
 
-	amountStr := strconv.FormatFloat(amount, 'f', -1, 64)
+// binanceOrderError maps Binance's unknown order errors to ErrOrderNotFound
+func binanceOrderError(err error) error {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == binanceUnknownOrderCode || apiErr.Code == binanceCancelRejectedCode) {
+		return fmt.Errorf("%w: %v", ErrOrderNotFound, err)
+	}
+	return err
+}
+
+// CancelOrder cancels orderID, ids that are not numeric are taken as client order ids
+func (b *BinanceVenue) CancelOrder(symbol, orderID string) error {
+	defer observeLatency("binance_cancel_order", time.Now())
+
+	service := b.client.NewCancelOrderService().Symbol(symbol)
+	if id, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		service = service.OrderID(id)
+	} else {
+		service = service.OrigClientOrderID(orderID)
+	}
+
+	_, err := service.Do(context.Background())
+	return binanceOrderError(err)
+}
+
+// GetOrderStatus looks up orderID, ids that are not numeric are taken as client
+// order ids. Closed orders the user data stream followed since they were placed
+// are served from it. Open ones are queried, as a cancel just sent may not have
+// been reported yet, along with the commissions of their trades. The lookup fails
+// when those can't be read, so a fill is never settled as if it paid none.
+func (b *BinanceVenue) GetOrderStatus(symbol, orderID string) (OrderResult, error) {
+	if order, ok := b.account.getOrder(symbol, orderID); ok && order.Status != OrderStatusNew && order.Status != OrderStatusPartiallyFilled {
+		return order, nil
+	}
+
+	defer observeLatency("binance_get_order", time.Now())
+
+	service := b.client.NewGetOrderService().Symbol(symbol)
+	if id, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		service = service.OrderID(id)
+	} else {
+		service = service.OrigClientOrderID(orderID)
+	}
 
-	// TODO: consider doing limit orders here
-	order, err := client.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeSell).Type(binance.OrderTypeMarket).Quantity(amountStr).Do(context.Background())
+	order, err := service.Do(context.Background())
 	if err != nil {
-		return 0, 0, err
+		return OrderResult{}, binanceOrderError(err)
 	}
 
-	soldPrice, err = strconv.ParseFloat(order.Fills[0].Price, 64)
+	executedQuantity, err := sdk.NewDecFromStr(order.ExecutedQuantity)
 	if err != nil {
-		return 0, 0, err
+		return OrderResult{}, err
 	}
 
-	soldAmount, err = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	// the average fill price is not part of the order, derive it from the quote quantity
+	price := sdk.ZeroDec()
+	if executedQuantity.IsPositive() {
+		quoteQuantity, err := sdk.NewDecFromStr(order.CummulativeQuoteQuantity)
+		if err != nil {
+			return OrderResult{}, err
+		}
+		price = quoteQuantity.Quo(executedQuantity)
+	}
+
+	// the order does not carry its commissions, they are read from its trades
+	commissions := map[string]sdk.Dec{}
+	if executedQuantity.IsPositive() {
+		commissions, err = b.orderCommissions(symbol, order.OrderID)
+		if err != nil {
+			return OrderResult{}, fmt.Errorf("error fetching commissions of order %d: %v", order.OrderID, err)
+		}
+	}
+
+	return OrderResult{
+		OrderID:          strconv.FormatInt(order.OrderID, 10),
+		ClientOrderID:    order.ClientOrderID,
+		Symbol:           order.Symbol,
+		Side:             OrderSide(order.Side),
+		Status:           OrderStatus(order.Status),
+		ExecutedQuantity: executedQuantity,
+		Price:            price,
+		Commissions:      commissions,
+	}, nil
+}
+
+// orderCommissions sums the commissions of orderID's trades per asset
+func (b *BinanceVenue) orderCommissions(symbol string, orderID int64) (map[string]sdk.Dec, error) {
+	defer observeLatency("binance_my_trades", time.Now())
+
+	trades, err := b.client.NewListTradesService().Symbol(symbol).OrderId(orderID).Do(context.Background())
 	if err != nil {
-		return 0, 0, err
+		return nil, err
+	}
+
+	fills := make([]*binance.Fill, len(trades))
+	for i, trade := range trades {
+		fills[i] = &binance.Fill{
+			TradeID:         trade.ID,
+			Price:           trade.Price,
+			Quantity:        trade.Quantity,
+			Commission:      trade.Commission,
+			CommissionAsset: trade.CommissionAsset,
+		}
+	}
+	_, commissions, err := aggregateFills(fills)
+	return commissions, err
+}
+
+// WatchBookTicker streams best bid and ask updates of symbols and calls onUpdate
+// with the symbol of every update until ctx is done, reconnecting when the stream
+// drops. It also keeps a local order book of symbols from the diff depth stream,
+// which GetDepth reads while it is fresh.
+func (b *BinanceVenue) WatchBookTicker(ctx context.Context, symbols []string, onUpdate func(symbol string)) {
+	b.market.watch(ctx, symbols, onUpdate)
+}
+
+// WatchAccount follows the account's balances and orders on the user data stream
+// until ctx is done, reconnecting when it drops. GetBalances and GetOrderStatus
+// read them while the stream is live instead of polling the API.
+func (b *BinanceVenue) WatchAccount(ctx context.Context) {
+	b.account.watch(ctx)
+}
+
+// WaitForOrder waits for an execution report moving orderID out of NEW or
+// PARTIALLY_FILLED, until ctx is done. ok is false when the stream is not
+// live or did not follow the order.
+func (b *BinanceVenue) WaitForOrder(ctx context.Context, symbol, orderID string) (OrderResult, bool) {
+	return b.account.waitForOrder(ctx, symbol, orderID)
+}
+
+func parsePriceLevels(levels []binance.Bid) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, len(levels))
+	for i, level := range levels {
+		price, err := sdk.NewDecFromStr(level.Price)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price level: %v", err)
+		}
+		quantity, err := sdk.NewDecFromStr(level.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price level: %v", err)
+		}
+		parsed[i] = PriceLevel{Price: price, Quantity: quantity}
+	}
+	return parsed, nil
+}
+
+// parseSymbolFilters reads the LOT_SIZE, MARKET_LOT_SIZE, PRICE_FILTER, NOTIONAL and
+// MIN_NOTIONAL filters of s. The two lot sizes are kept apart, see ForOrderType.
+func parseSymbolFilters(s binance.Symbol) (SymbolFilters, error) {
+	var filters SymbolFilters
+	var err error
+	parse := func(dst *sdk.Dec, value string) {
+		if err != nil || value == "" {
+			return
+		}
+		var d sdk.Dec
+		d, err = sdk.NewDecFromStr(value)
+		if err != nil {
+			err = fmt.Errorf("invalid filter value %s: %v", value, err)
+			return
+		}
+		*dst = d
+	}
+
+	if lotSize := s.LotSizeFilter(); lotSize != nil {
+		parse(&filters.MinQuantity, lotSize.MinQuantity)
+		parse(&filters.MaxQuantity, lotSize.MaxQuantity)
+		parse(&filters.StepSize, lotSize.StepSize)
+	}
+	if marketLotSize := s.MarketLotSizeFilter(); marketLotSize != nil {
+		parse(&filters.MarketMinQuantity, marketLotSize.MinQuantity)
+		parse(&filters.MarketMaxQuantity, marketLotSize.MaxQuantity)
+		parse(&filters.MarketStepSize, marketLotSize.StepSize)
+	}
+
+	if priceFilter := s.PriceFilter(); priceFilter != nil {
+		parse(&filters.MinPrice, priceFilter.MinPrice)
+		parse(&filters.MaxPrice, priceFilter.MaxPrice)
+		parse(&filters.TickSize, priceFilter.TickSize)
+	}
+
+	// the notional bounds always apply to limit orders, the flags only decide whether they apply to market orders
+	if notional := s.NotionalFilter(); notional != nil {
+		parse(&filters.MinNotional, notional.MinNotional)
+		parse(&filters.MaxNotional, notional.MaxNotional)
+		filters.MinNotionalAppliesMarket = notional.ApplyMinToMarket
+		filters.MaxNotionalAppliesMarket = notional.ApplyMaxToMarket
+	}
+
+	// MIN_NOTIONAL is the older form of NOTIONAL, still returned for some symbols
+	for _, filter := range s.Filters {
+		if filter["filterType"] != string(binance.SymbolFilterTypeMinNotional) {
+			continue
+		}
+		if minNotional, ok := filter["minNotional"].(string); ok {
+			parse(&filters.MinNotional, minNotional)
+		}
+		applyToMarket, ok := filter["applyToMarket"].(bool)
+		filters.MinNotionalAppliesMarket = !ok || applyToMarket
 	}
 
-	return soldAmount, soldPrice, nil
+	return filters, err
 }
 
 func filterBalances(balances []binance.Balance, assets []string) []binance.Balance {
@@ -0,0 +1,367 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// binanceAccount is the account's balances and orders as pushed by the user data
+// stream. It is only read while live, from when the stream is connected and the
+// balances seeded until it drops, as events are missed while disconnected.
+type binanceAccount struct {
+	client *binance.Client
+
+	mu   sync.Mutex
+	live bool
+
+	balances map[string]Balance
+	// balanceTimes is when each balance was last pushed, in ms, so the seed
+	// snapshot does not overwrite newer pushes
+	balanceTimes map[string]int64
+
+	orders map[string]*streamedOrder
+	// clientOrders maps client order ids to order ids
+	clientOrders map[string]string
+	// updated is closed and replaced on every order update, see waitForOrder
+	updated chan struct{}
+}
+
+// streamedOrder is an order as built from its execution reports
+type streamedOrder struct {
+	result OrderResult
+	// complete is set for orders seen from their first report on, the others
+	// are missing fills and are looked up over REST instead
+	complete bool
+	// closedAt is when the order stopped resting, zero while it rests
+	closedAt time.Time
+}
+
+func newBinanceAccount(client *binance.Client) *binanceAccount {
+	return &binanceAccount{
+		client:  client,
+		updated: make(chan struct{}),
+	}
+}
+
+// watch keeps the user data stream connected until ctx is done. Each connection
+// starts a listen key, kept alive every binanceListenKeyKeepalive, and seeds the
+// balances from the account before the state is read.
+func (a *binanceAccount) watch(ctx context.Context) {
+	for {
+		if err := a.serve(ctx); err != nil {
+			log.Println("Binance user data stream error:", err)
+		}
+		a.setLive(false)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamReconnectDelay):
+		}
+	}
+}
+
+// serve runs a single connection of the user data stream, returning when it drops or ctx is done
+func (a *binanceAccount) serve(ctx context.Context) error {
+	listenKey, err := a.client.NewStartUserStreamService().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting listen key: %v", err)
+	}
+	defer func() {
+		if err := a.client.NewCloseUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+			log.Println("Error closing Binance listen key:", err)
+		}
+	}()
+
+	a.reset()
+	doneC, stopC, err := binance.WsUserDataServe(listenKey, a.onEvent, func(err error) {
+		log.Println("Binance user data stream error:", err)
+	})
+	if err != nil {
+		return fmt.Errorf("error connecting: %v", err)
+	}
+	defer close(stopC)
+
+	if err := a.seedBalances(ctx); err != nil {
+		return err
+	}
+	a.setLive(true)
+	log.Println("Binance user data stream connected")
+
+	keepalive := time.NewTicker(binanceListenKeyKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-doneC:
+			return fmt.Errorf("stream closed")
+		case <-keepalive.C:
+			if err := a.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx); err != nil {
+				return fmt.Errorf("error keeping listen key alive: %v", err)
+			}
+		}
+	}
+}
+
+// reset drops the state of a previous connection
+func (a *binanceAccount) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.balances = make(map[string]Balance)
+	a.balanceTimes = make(map[string]int64)
+	a.orders = make(map[string]*streamedOrder)
+	a.clientOrders = make(map[string]string)
+}
+
+func (a *binanceAccount) setLive(live bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.live = live
+	a.notify()
+}
+
+// seedBalances sets the balances the stream has not pushed since the account snapshot
+func (a *binanceAccount) seedBalances(ctx context.Context) error {
+	start := time.Now()
+	account, err := a.client.NewGetAccountService().Do(ctx)
+	observeLatency("binance_account", start)
+	if err != nil {
+		return fmt.Errorf("error fetching account: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, balance := range account.Balances {
+		if a.balanceTimes[balance.Asset] > int64(account.UpdateTime) {
+			continue
+		}
+		parsed, err := parseBinanceBalance(balance.Free, balance.Locked)
+		if err != nil {
+			return fmt.Errorf("invalid %s balance: %v", balance.Asset, err)
+		}
+		a.balances[balance.Asset] = parsed
+	}
+	return nil
+}
+
+// onEvent applies a user data event to the account state
+func (a *binanceAccount) onEvent(event *binance.WsUserDataEvent) {
+	userDataEvents.WithLabelValues(string(event.Event)).Inc()
+
+	switch event.Event {
+	case binance.UserDataEventTypeOutboundAccountPosition:
+		a.onAccountPosition(event.AccountUpdate)
+	case binance.UserDataEventTypeExecutionReport:
+		a.onExecutionReport(event.OrderUpdate)
+	}
+}
+
+// onAccountPosition sets the balances of the assets that changed
+func (a *binanceAccount) onAccountPosition(update binance.WsAccountUpdateList) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, balance := range update.WsAccountUpdates {
+		parsed, err := parseBinanceBalance(balance.Free, balance.Locked)
+		if err != nil {
+			log.Println("Error parsing Binance", balance.Asset, "balance update:", err)
+			continue
+		}
+		a.balances[balance.Asset] = parsed
+		a.balanceTimes[balance.Asset] = update.AccountUpdateTime
+	}
+}
+
+// onExecutionReport updates the order the report is about with its status,
+// cumulative fills and the commission of the trade it reports, if any
+func (a *binanceAccount) onExecutionReport(update binance.WsOrderUpdate) {
+	executed, err := sdk.NewDecFromStr(update.FilledVolume)
+	if err != nil {
+		log.Println("Error parsing Binance order", update.Id, "update:", err)
+		return
+	}
+	quoteExecuted, err := sdk.NewDecFromStr(update.FilledQuoteVolume)
+	if err != nil {
+		log.Println("Error parsing Binance order", update.Id, "update:", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	orderID := strconv.FormatInt(update.Id, 10)
+	order, ok := a.orders[orderID]
+	if !ok {
+		// cancels report the cancel request's client id, the order's own is the original one
+		clientOrderID := update.ClientOrderId
+		if update.OrigCustomOrderId != "" {
+			clientOrderID = update.OrigCustomOrderId
+		}
+		order = &streamedOrder{
+			result: OrderResult{
+				OrderID:       orderID,
+				ClientOrderID: clientOrderID,
+				Symbol:        update.Symbol,
+				Side:          OrderSide(update.Side),
+				Commissions:   make(map[string]sdk.Dec),
+			},
+			complete: update.ExecutionType == binanceExecutionNew,
+		}
+		a.orders[orderID] = order
+		a.clientOrders[clientOrderID] = orderID
+	}
+
+	result := &order.result
+	result.Status = OrderStatus(update.Status)
+	result.ExecutedQuantity = executed
+	result.Price = sdk.ZeroDec()
+	if executed.IsPositive() {
+		result.Price = quoteExecuted.Quo(executed)
+	}
+	if update.ExecutionType == binanceExecutionTrade && update.FeeAsset != "" {
+		commission, err := sdk.NewDecFromStr(update.FeeCost)
+		if err != nil {
+			log.Println("Error parsing Binance order", update.Id, "commission:", err)
+		} else {
+			if total, ok := result.Commissions[update.FeeAsset]; ok {
+				commission = commission.Add(total)
+			}
+			result.Commissions[update.FeeAsset] = commission
+		}
+	}
+
+	if result.Status == OrderStatusPartiallyFilled {
+		log.Println("Binance order", orderID, "partially filled,", executed, "of", update.Volume)
+	}
+	if result.Status != OrderStatusNew && result.Status != OrderStatusPartiallyFilled && order.closedAt.IsZero() {
+		order.closedAt = time.Now()
+	}
+	a.pruneOrders()
+	a.notify()
+}
+
+// pruneOrders drops the orders closed for longer than binanceClosedOrderTTL that
+// were never read, must be called with mu held
+func (a *binanceAccount) pruneOrders() {
+	for orderID, order := range a.orders {
+		if !order.closedAt.IsZero() && time.Since(order.closedAt) > binanceClosedOrderTTL {
+			a.dropOrder(orderID)
+		}
+	}
+}
+
+// dropOrder forgets an order, must be called with mu held
+func (a *binanceAccount) dropOrder(orderID string) {
+	if order, ok := a.orders[orderID]; ok {
+		delete(a.clientOrders, order.result.ClientOrderID)
+		delete(a.orders, orderID)
+	}
+}
+
+// notify wakes the waitForOrder calls, must be called with mu held
+func (a *binanceAccount) notify() {
+	close(a.updated)
+	a.updated = make(chan struct{})
+}
+
+// getBalances returns the streamed balances of assets, ok is false while the stream is not live
+func (a *binanceAccount) getBalances(assets []string) (map[string]Balance, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.live {
+		return nil, false
+	}
+	balances := make(map[string]Balance, len(assets))
+	for _, asset := range assets {
+		balances[asset] = NewBalance(a.balances[asset].Free, a.balances[asset].Locked)
+	}
+	return balances, true
+}
+
+// getOrder returns the streamed state of an order by id or client order id. ok
+// is false while the stream is not live or when the order was not followed
+// from its first report. Closed orders are forgotten once read, later lookups
+// go to the API.
+func (a *binanceAccount) getOrder(symbol, orderID string) (OrderResult, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.lookupOrder(symbol, orderID)
+}
+
+// lookupOrder is getOrder with mu held
+func (a *binanceAccount) lookupOrder(symbol, orderID string) (OrderResult, bool) {
+	result, ok := a.peekOrder(symbol, orderID)
+	if ok && result.Status != OrderStatusNew && result.Status != OrderStatusPartiallyFilled {
+		a.dropOrder(result.OrderID)
+	}
+	return result, ok
+}
+
+// peekOrder is lookupOrder without forgetting closed orders
+func (a *binanceAccount) peekOrder(symbol, orderID string) (OrderResult, bool) {
+	if !a.live {
+		return OrderResult{}, false
+	}
+	order, ok := a.orders[orderID]
+	if !ok {
+		order, ok = a.orders[a.clientOrders[orderID]]
+	}
+	if !ok || !order.complete || order.result.Symbol != symbol {
+		return OrderResult{}, false
+	}
+
+	result := order.result
+	result.Commissions = make(map[string]sdk.Dec, len(order.result.Commissions))
+	for asset, commission := range order.result.Commissions {
+		result.Commissions[asset] = commission
+	}
+	return result, true
+}
+
+// waitForOrder blocks until the order stops resting or ctx is done and
+// returns its latest state, see getOrder for ok
+func (a *binanceAccount) waitForOrder(ctx context.Context, symbol, orderID string) (OrderResult, bool) {
+	for {
+		a.mu.Lock()
+		order, ok := a.lookupOrder(symbol, orderID)
+		live, updated := a.live, a.updated
+		a.mu.Unlock()
+
+		if !live {
+			return OrderResult{}, false
+		}
+		if ok && order.Status != OrderStatusNew && order.Status != OrderStatusPartiallyFilled {
+			return order, true
+		}
+
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return order, ok
+		}
+	}
+}
+
+func parseBinanceBalance(free, locked string) (Balance, error) {
+	parsedFree, err := sdk.NewDecFromStr(free)
+	if err != nil {
+		return Balance{}, fmt.Errorf("invalid free balance %q: %v", free, err)
+	}
+	parsedLocked, err := sdk.NewDecFromStr(locked)
+	if err != nil {
+		return Balance{}, fmt.Errorf("invalid locked balance %q: %v", locked, err)
+	}
+	return NewBalance(parsedFree, parsedLocked), nil
+}
@@ -0,0 +1,209 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned for Binance requests made while the API asked the
+// bot to back off
+var ErrRateLimited = errors.New("rate limited")
+
+// binanceRateLimit is one of the limits Binance counts REST requests against.
+// Binance reports the count of the current window in header after each request.
+type binanceRateLimit struct {
+	name     string
+	header   string
+	limit    float64
+	interval time.Duration
+	// orders is set for limits that count orders placed rather than request weight
+	orders bool
+}
+
+var binanceRateLimits = []binanceRateLimit{
+	{name: "weight_1m", header: "X-MBX-USED-WEIGHT-1M", limit: binanceWeightLimit, interval: time.Minute},
+	{name: "orders_10s", header: "X-MBX-ORDER-COUNT-10S", limit: binanceOrderLimit10s, interval: 10 * time.Second, orders: true},
+	{name: "orders_1d", header: "X-MBX-ORDER-COUNT-1D", limit: binanceOrderLimit1d, interval: 24 * time.Hour, orders: true},
+}
+
+// tokenBucket holds up to capacity tokens, refilled at capacity per interval
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	interval time.Duration
+	updated  time.Time
+}
+
+func (t *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(t.updated)
+	t.tokens = min(t.capacity, t.tokens+t.capacity*elapsed.Seconds()/t.interval.Seconds())
+	t.updated = now
+}
+
+// wait returns how long until cost tokens are available
+func (t *tokenBucket) wait(cost float64) time.Duration {
+	cost = min(cost, t.capacity)
+	if t.tokens >= cost {
+		return 0
+	}
+	return time.Duration((cost - t.tokens) / t.capacity * float64(t.interval))
+}
+
+// binanceLimiter is the transport of every Binance REST call. It keeps a token
+// bucket per rate limit, sized at binanceRateLimitShare of the limit, and waits
+// for a request's estimated cost before sending it. The buckets are trued up to
+// the counts Binance reports, which include calls the estimate got wrong. A 429
+// or 418 makes every request fail with ErrRateLimited until its Retry-After.
+type binanceLimiter struct {
+	next http.RoundTripper
+
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	bannedUntil time.Time
+}
+
+func newBinanceLimiter(next http.RoundTripper) *binanceLimiter {
+	now := time.Now()
+	buckets := make(map[string]*tokenBucket, len(binanceRateLimits))
+	for _, limit := range binanceRateLimits {
+		capacity := limit.limit * binanceRateLimitShare
+		buckets[limit.name] = &tokenBucket{capacity: capacity, tokens: capacity, interval: limit.interval, updated: now}
+		rateLimitRemaining.WithLabelValues(limit.name).Set(capacity)
+	}
+	return &binanceLimiter{next: next, buckets: buckets}
+}
+
+func (l *binanceLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := l.acquire(req); err != nil {
+		return nil, err
+	}
+
+	res, err := l.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	l.observe(res)
+	return res, nil
+}
+
+// acquire waits until the buckets hold the request's cost and takes it
+func (l *binanceLimiter) acquire(req *http.Request) error {
+	weight, orders := binanceRequestCost(req)
+	for {
+		l.mu.Lock()
+		if until := l.bannedUntil; time.Now().Before(until) {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: Binance asked to back off until %s", ErrRateLimited, until.Format(time.RFC3339))
+		}
+
+		now := time.Now()
+		var wait time.Duration
+		for _, limit := range binanceRateLimits {
+			bucket := l.buckets[limit.name]
+			bucket.refill(now)
+			if cost := limitCost(limit, weight, orders); cost > 0 {
+				wait = max(wait, bucket.wait(cost))
+			}
+		}
+		if wait == 0 {
+			for _, limit := range binanceRateLimits {
+				bucket := l.buckets[limit.name]
+				bucket.tokens = max(0, bucket.tokens-limitCost(limit, weight, orders))
+				rateLimitRemaining.WithLabelValues(limit.name).Set(bucket.tokens)
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		rateLimitWaits.WithLabelValues(req.URL.Path).Inc()
+		select {
+		case <-req.Context().Done():
+			return req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// observe trues the buckets up to the counts Binance reported and backs off
+// when Binance rejected the request for exceeding a limit
+func (l *binanceLimiter) observe(res *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for _, limit := range binanceRateLimits {
+		used, err := strconv.ParseFloat(res.Header.Get(limit.header), 64)
+		if err != nil {
+			continue
+		}
+		bucket := l.buckets[limit.name]
+		bucket.refill(now)
+		bucket.tokens = max(0, min(bucket.tokens, bucket.capacity-used))
+		rateLimitRemaining.WithLabelValues(limit.name).Set(bucket.tokens)
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != binanceIPBannedStatus {
+		return
+	}
+	retryAfter := binanceDefaultRetryAfter
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	if until := now.Add(retryAfter); until.After(l.bannedUntil) {
+		l.bannedUntil = until
+	}
+	rateLimitRejections.WithLabelValues(strconv.Itoa(res.StatusCode)).Inc()
+	log.Println("Binance rejected", res.Request.URL.Path, "with status", res.StatusCode, ", backing off for", retryAfter)
+}
+
+// limitCost is what a request of weight placing orders costs against limit
+func limitCost(limit binanceRateLimit, weight, orders float64) float64 {
+	if limit.orders {
+		return orders
+	}
+	return weight
+}
+
+// binanceRequestCost estimates the request weight of a call and the orders it
+// places. Wallet endpoints under /sapi are counted against separate limits and
+// cost nothing here.
+func binanceRequestCost(req *http.Request) (weight, orders float64) {
+	path := req.URL.Path
+	switch {
+	case path == "/api/v3/order" && req.Method == http.MethodPost:
+		return 1, 1
+	case path == "/api/v3/order" && req.Method == http.MethodGet:
+		return 4, 0
+	case path == "/api/v3/myTrades" && req.URL.Query().Has("orderId"):
+		return 5, 0
+	case path == "/api/v3/myTrades":
+		return 20, 0
+	case path == "/api/v3/depth":
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		switch {
+		case limit > 1000:
+			return 250, 0
+		case limit > 500:
+			return 50, 0
+		case limit > 100:
+			return 25, 0
+		default:
+			return 5, 0
+		}
+	case path == "/api/v3/account", path == "/api/v3/account/commission", path == "/api/v3/exchangeInfo":
+		return 20, 0
+	case path == "/api/v3/ticker/price", path == "/api/v3/userDataStream":
+		return 2, 0
+	case strings.HasPrefix(path, "/api/"):
+		return 1, 0
+	default:
+		return 0, 0
+	}
+}
@@ -0,0 +1,192 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRoundTripper answers every request with status and header
+type fakeRoundTripper struct {
+	status int
+	header http.Header
+	calls  int
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     f.header,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		cost       float64
+		wantTokens float64
+		wantWait   time.Duration
+	}{
+		{name: "enough tokens", tokens: 80, cost: 8, wantTokens: 80},
+		{name: "empty", cost: 8, wantWait: time.Second},
+		{name: "refilled in part", elapsed: 5 * time.Second, cost: 60, wantTokens: 40, wantWait: 2500 * time.Millisecond},
+		{name: "refilled to capacity", tokens: 40, elapsed: time.Minute, cost: 80, wantTokens: 80},
+		{name: "cost above capacity", cost: 200, wantWait: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := &tokenBucket{capacity: 80, tokens: tt.tokens, interval: 10 * time.Second, updated: start}
+			bucket.refill(start.Add(tt.elapsed))
+			if bucket.tokens != tt.wantTokens {
+				t.Errorf("tokens = %f, want %f", bucket.tokens, tt.wantTokens)
+			}
+			if wait := bucket.wait(tt.cost); wait != tt.wantWait {
+				t.Errorf("wait(%f) = %s, want %s", tt.cost, wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestBinanceLimiterObserve(t *testing.T) {
+	weightCapacity := binanceWeightLimit * binanceRateLimitShare
+	ordersCapacity := binanceOrderLimit10s * binanceRateLimitShare
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		status     int
+		header     map[string]string
+		wantWeight float64
+		wantOrders float64
+		// wantBanFor is how long later requests are rejected, none when zero
+		wantBanFor time.Duration
+	}{
+		{
+			name:       "estimate taken",
+			method:     http.MethodGet,
+			path:       "/api/v3/account",
+			status:     http.StatusOK,
+			wantWeight: weightCapacity - 20,
+			wantOrders: ordersCapacity,
+		},
+		{
+			name:       "used weight reported",
+			method:     http.MethodGet,
+			path:       "/api/v3/account",
+			status:     http.StatusOK,
+			header:     map[string]string{"X-MBX-USED-WEIGHT-1M": "4000"},
+			wantWeight: weightCapacity - 4000,
+			wantOrders: ordersCapacity,
+		},
+		{
+			name:       "lower report than the estimate",
+			method:     http.MethodGet,
+			path:       "/api/v3/account",
+			status:     http.StatusOK,
+			header:     map[string]string{"X-MBX-USED-WEIGHT-1M": "5"},
+			wantWeight: weightCapacity - 20,
+			wantOrders: ordersCapacity,
+		},
+		{
+			name:       "order count reported",
+			method:     http.MethodPost,
+			path:       "/api/v3/order",
+			status:     http.StatusOK,
+			header:     map[string]string{"X-MBX-USED-WEIGHT-1M": "1", "X-MBX-ORDER-COUNT-10S": "79"},
+			wantWeight: weightCapacity - 1,
+			wantOrders: ordersCapacity - 79,
+		},
+		{
+			name:       "429 with Retry-After",
+			method:     http.MethodGet,
+			path:       "/api/v3/account",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"X-MBX-USED-WEIGHT-1M": "6000", "Retry-After": "30"},
+			wantWeight: 0,
+			wantOrders: ordersCapacity,
+			wantBanFor: 30 * time.Second,
+		},
+		{
+			name:       "418 without Retry-After",
+			method:     http.MethodGet,
+			path:       "/api/v3/account",
+			status:     binanceIPBannedStatus,
+			wantWeight: weightCapacity - 20,
+			wantOrders: ordersCapacity,
+			wantBanFor: binanceDefaultRetryAfter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeRoundTripper{status: tt.status, header: make(http.Header)}
+			for key, value := range tt.header {
+				next.header.Set(key, value)
+			}
+			limiter := newBinanceLimiter(next)
+
+			req, err := http.NewRequest(tt.method, "https://api.binance.com"+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			if _, err := limiter.RoundTrip(req); err != nil {
+				t.Fatalf("RoundTrip() = %v", err)
+			}
+
+			// the buckets refill a little between the request and the check
+			if weight := limiter.buckets["weight_1m"].tokens; math.Abs(weight-tt.wantWeight) > 1 {
+				t.Errorf("weight tokens = %f, want %f", weight, tt.wantWeight)
+			}
+			if orders := limiter.buckets["orders_10s"].tokens; math.Abs(orders-tt.wantOrders) > 1 {
+				t.Errorf("order tokens = %f, want %f", orders, tt.wantOrders)
+			}
+
+			if tt.wantBanFor == 0 {
+				if !limiter.bannedUntil.IsZero() {
+					t.Errorf("banned until %s, want no ban", limiter.bannedUntil)
+				}
+				return
+			}
+			if banFor := limiter.bannedUntil.Sub(start); banFor < tt.wantBanFor || banFor > tt.wantBanFor+time.Second {
+				t.Errorf("banned for %s, want %s", banFor, tt.wantBanFor)
+			}
+			if _, err := limiter.RoundTrip(req); !errors.Is(err, ErrRateLimited) {
+				t.Errorf("RoundTrip() while banned = %v, want %v", err, ErrRateLimited)
+			}
+			if next.calls != 1 {
+				t.Errorf("sent %d requests, want the one before the ban", next.calls)
+			}
+		})
+	}
+}
+
+func TestBinanceLimiterWaitsForTokens(t *testing.T) {
+	next := &fakeRoundTripper{status: http.StatusOK, header: make(http.Header)}
+	limiter := newBinanceLimiter(next)
+	limiter.buckets["weight_1m"].tokens = 0
+
+	// 20 weight takes 250ms to refill, longer than the request may wait
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.binance.com/api/v3/account", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if next.calls != 0 {
+		t.Errorf("sent %d requests, want none", next.calls)
+	}
+}
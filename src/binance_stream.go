@@ -0,0 +1,401 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// binanceMarketData keeps a local order book of each watched symbol from the
+// diff depth stream on top of a REST snapshot, following Binance's guide to
+// managing a local order book. The book ticker stream, which shares the depth's
+// update ids, is laid over the top of the book between depth updates.
+type binanceMarketData struct {
+	client *binance.Client
+
+	mu         sync.Mutex
+	books      map[string]*localBook
+	staleAfter time.Duration
+
+	resync chan string
+}
+
+// localBook is a symbol's order book as kept from the streams
+type localBook struct {
+	// synced is set once a snapshot was taken and the buffered diffs after it
+	// applied. Unsynced books buffer the diffs they receive.
+	synced    bool
+	resyncing bool
+	buffered  []*binance.WsDepthEvent
+
+	lastUpdateID int64
+	bids         map[string]PriceLevel
+	asks         map[string]PriceLevel
+
+	// eventTime is when Binance sent the last diff applied, or when the snapshot was fetched
+	eventTime time.Time
+
+	// ticker is the latest best bid and ask, only used while newer than the depth
+	tickerUpdateID int64
+	bestBid        PriceLevel
+	bestAsk        PriceLevel
+}
+
+func newBinanceMarketData(client *binance.Client) *binanceMarketData {
+	return &binanceMarketData{
+		client:     client,
+		books:      make(map[string]*localBook),
+		staleAfter: binanceMarketDataStaleAfter,
+		resync:     make(chan string, 16),
+	}
+}
+
+func newLocalBook() *localBook {
+	return &localBook{
+		bids: make(map[string]PriceLevel),
+		asks: make(map[string]PriceLevel),
+	}
+}
+
+// setStaleAfter sets how far the depth stream may lag before books are stale
+func (m *binanceMarketData) setStaleAfter(staleAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.staleAfter = staleAfter
+}
+
+// watch streams the depth and book ticker of symbols until ctx is done, calling
+// onTicker on every best bid or ask update. Books are resynced from a snapshot
+// whenever a diff is missed or the stream reconnects.
+func (m *binanceMarketData) watch(ctx context.Context, symbols []string, onTicker func(symbol string)) {
+	m.mu.Lock()
+	for _, symbol := range symbols {
+		m.books[symbol] = newLocalBook()
+	}
+	m.mu.Unlock()
+
+	go m.resyncBooks(ctx)
+
+	go serveStream(ctx, "depth", func() (chan struct{}, chan struct{}, error) {
+		return binance.WsCombinedDepthServe100Ms(symbols, m.onDepth, func(err error) {
+			log.Println("Binance depth stream error:", err)
+		})
+	}, func() {
+		// diffs were missed while disconnected
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for symbol, book := range m.books {
+			m.unsync(symbol, book, "reconnect")
+		}
+	})
+
+	serveStream(ctx, "book ticker", func() (chan struct{}, chan struct{}, error) {
+		return binance.WsCombinedBookTickerServe(symbols, func(event *binance.WsBookTickerEvent) {
+			m.onTicker(event)
+			onTicker(event.Symbol)
+		}, func(err error) {
+			log.Println("Binance book ticker stream error:", err)
+		})
+	}, nil)
+}
+
+// serveStream keeps a websocket stream served until ctx is done, reconnecting
+// streamReconnectDelay after it drops. onDisconnect, when set, is called every
+// time the stream drops.
+func serveStream(ctx context.Context, name string, serve func() (doneC, stopC chan struct{}, err error), onDisconnect func()) {
+	for {
+		doneC, stopC, err := serve()
+		if err != nil {
+			log.Println("Error connecting to Binance", name, "stream:", err)
+		} else {
+			select {
+			case <-ctx.Done():
+				close(stopC)
+				return
+			case <-doneC:
+			}
+		}
+		if onDisconnect != nil {
+			onDisconnect()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamReconnectDelay):
+		}
+	}
+}
+
+// depth returns up to limit levels of each side of symbol's local book. ok is
+// false when the book is not synced or the depth stream lags more than staleAfter.
+func (m *binanceMarketData) depth(symbol string, limit int) (book OrderBook, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	local, watched := m.books[symbol]
+	if !watched || !local.synced {
+		return OrderBook{}, false
+	}
+	lag := time.Since(local.eventTime)
+	marketDataLag.WithLabelValues(symbol).Set(lag.Seconds())
+	if lag > m.staleAfter {
+		return OrderBook{}, false
+	}
+
+	bids := sortedLevels(local.bids, true)
+	asks := sortedLevels(local.asks, false)
+	if local.tickerUpdateID > local.lastUpdateID {
+		bids = overlayBestLevel(bids, local.bestBid, true)
+		asks = overlayBestLevel(asks, local.bestAsk, false)
+	}
+
+	if limit > 0 && len(bids) > limit {
+		bids = bids[:limit]
+	}
+	if limit > 0 && len(asks) > limit {
+		asks = asks[:limit]
+	}
+	return OrderBook{Bids: bids, Asks: asks}, true
+}
+
+// onDepth applies a diff to its symbol's book, buffering it while the book is
+// not synced. A diff that does not follow the last one applied unsyncs the book.
+func (m *binanceMarketData) onDepth(event *binance.WsDepthEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[event.Symbol]
+	if !ok {
+		return
+	}
+	if !book.synced {
+		book.buffered = append(book.buffered, event)
+		m.requestResync(event.Symbol, book)
+		return
+	}
+
+	if err := book.apply(event); err != nil {
+		log.Println("Binance", event.Symbol, "order book out of sync:", err)
+		m.unsync(event.Symbol, book, "gap")
+		book.buffered = append(book.buffered, event)
+		m.requestResync(event.Symbol, book)
+	}
+}
+
+// onTicker records the best bid and ask of a book ticker update
+func (m *binanceMarketData) onTicker(event *binance.WsBookTickerEvent) {
+	bestBid, err := parseTickerLevel(event.BestBidPrice, event.BestBidQty)
+	if err != nil {
+		log.Println("Error parsing Binance", event.Symbol, "book ticker:", err)
+		return
+	}
+	bestAsk, err := parseTickerLevel(event.BestAskPrice, event.BestAskQty)
+	if err != nil {
+		log.Println("Error parsing Binance", event.Symbol, "book ticker:", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[event.Symbol]
+	if !ok || event.UpdateID <= book.tickerUpdateID {
+		return
+	}
+	book.tickerUpdateID, book.bestBid, book.bestAsk = event.UpdateID, bestBid, bestAsk
+}
+
+// unsync drops the book's state until it is resynced, must be called with mu held
+func (m *binanceMarketData) unsync(symbol string, book *localBook, reason string) {
+	if book.synced {
+		orderBookResyncs.WithLabelValues(symbol, reason).Inc()
+	}
+	book.synced = false
+	book.buffered = nil
+}
+
+// requestResync queues a snapshot of the book unless one is pending, must be called with mu held
+func (m *binanceMarketData) requestResync(symbol string, book *localBook) {
+	if book.resyncing {
+		return
+	}
+	select {
+	case m.resync <- symbol:
+		book.resyncing = true
+	default:
+	}
+}
+
+// resyncBooks takes a snapshot of every book queued for a resync until ctx is done
+func (m *binanceMarketData) resyncBooks(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case symbol := <-m.resync:
+			err := m.resyncBook(symbol)
+
+			m.mu.Lock()
+			book := m.books[symbol]
+			book.resyncing = false
+			if err != nil {
+				log.Println("Error resyncing Binance", symbol, "order book:", err)
+				// retried on the next diff, after a pause so a failing snapshot is not hammered
+				book.buffered = nil
+			}
+			m.mu.Unlock()
+
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(binanceResyncDelay):
+				}
+			}
+		}
+	}
+}
+
+// resyncBook replaces symbol's book with a REST snapshot and applies the diffs
+// buffered since, which have to pick up where the snapshot ends
+func (m *binanceMarketData) resyncBook(symbol string) error {
+	start := time.Now()
+	snapshot, err := m.client.NewDepthService().Symbol(symbol).Limit(binanceDepthSnapshotLimit).Do(context.Background())
+	observeLatency("binance_depth", start)
+	if err != nil {
+		return fmt.Errorf("error fetching depth snapshot: %v", err)
+	}
+	bids, err := parsePriceLevels(snapshot.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parsePriceLevels(snapshot.Asks)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book := m.books[symbol]
+	book.bids, book.asks = make(map[string]PriceLevel, len(bids)), make(map[string]PriceLevel, len(asks))
+	for _, level := range bids {
+		book.bids[level.Price.String()] = level
+	}
+	for _, level := range asks {
+		book.asks[level.Price.String()] = level
+	}
+	book.lastUpdateID = snapshot.LastUpdateID
+
+	buffered := book.buffered
+	book.buffered = nil
+	for _, event := range buffered {
+		if err := book.apply(event); err != nil {
+			return fmt.Errorf("snapshot %d does not line up with the stream: %v", snapshot.LastUpdateID, err)
+		}
+	}
+	// the snapshot is as fresh as when it was fetched, unless a buffered diff is newer
+	book.eventTime = start
+	if len(buffered) > 0 {
+		if last := time.UnixMilli(buffered[len(buffered)-1].Time); last.After(start) {
+			book.eventTime = last
+		}
+	}
+	book.synced = true
+	log.Println("Binance", symbol, "order book synced at update", book.lastUpdateID)
+	return nil
+}
+
+// apply applies a diff to the book. Diffs the book already holds are skipped,
+// and a diff starting after the next update id means updates were missed.
+func (b *localBook) apply(event *binance.WsDepthEvent) error {
+	if event.LastUpdateID <= b.lastUpdateID {
+		return nil
+	}
+	if event.FirstUpdateID > b.lastUpdateID+1 {
+		return fmt.Errorf("updates %d to %d were missed", b.lastUpdateID+1, event.FirstUpdateID-1)
+	}
+
+	bids, err := parsePriceLevels(event.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parsePriceLevels(event.Asks)
+	if err != nil {
+		return err
+	}
+	applyLevels(b.bids, bids)
+	applyLevels(b.asks, asks)
+
+	b.lastUpdateID = event.LastUpdateID
+	b.eventTime = time.UnixMilli(event.Time)
+	return nil
+}
+
+// applyLevels sets the quantity of each level, removing the ones with none left
+func applyLevels(side map[string]PriceLevel, levels []PriceLevel) {
+	for _, level := range levels {
+		key := level.Price.String()
+		if level.Quantity.IsZero() {
+			delete(side, key)
+			continue
+		}
+		side[key] = level
+	}
+}
+
+// sortedLevels returns the levels of a side, bids by descending and asks by ascending price
+func sortedLevels(side map[string]PriceLevel, descending bool) []PriceLevel {
+	levels := make([]PriceLevel, 0, len(side))
+	for _, level := range side {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price.GT(levels[j].Price)
+		}
+		return levels[i].Price.LT(levels[j].Price)
+	})
+	return levels
+}
+
+// overlayBestLevel puts a newer best level from the book ticker on top of a
+// sorted side, dropping the levels it shows were taken out
+func overlayBestLevel(levels []PriceLevel, best PriceLevel, descending bool) []PriceLevel {
+	if !isSetDec(best.Price) || !best.Quantity.IsPositive() {
+		return levels
+	}
+
+	i := 0
+	for i < len(levels) {
+		price := levels[i].Price
+		if (descending && price.LTE(best.Price)) || (!descending && price.GTE(best.Price)) {
+			break
+		}
+		i++
+	}
+	if i < len(levels) && levels[i].Price.Equal(best.Price) {
+		i++
+	}
+	return append([]PriceLevel{best}, levels[i:]...)
+}
+
+func parseTickerLevel(price, quantity string) (PriceLevel, error) {
+	parsedPrice, err := sdk.NewDecFromStr(price)
+	if err != nil {
+		return PriceLevel{}, fmt.Errorf("invalid price %q: %v", price, err)
+	}
+	parsedQuantity, err := sdk.NewDecFromStr(quantity)
+	if err != nil {
+		return PriceLevel{}, fmt.Errorf("invalid quantity %q: %v", quantity, err)
+	}
+	return PriceLevel{Price: parsedPrice, Quantity: parsedQuantity}, nil
+}
@@ -0,0 +1,193 @@
+package src
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// testDepthEvent returns a BTCUSDT diff setting the bid at 60000 and the ask at
+// 60010 to the given quantities, a side left empty is not updated
+func testDepthEvent(firstUpdateID, lastUpdateID int64, bidQuantity, askQuantity string) *binance.WsDepthEvent {
+	event := &binance.WsDepthEvent{Symbol: "BTCUSDT", FirstUpdateID: firstUpdateID, LastUpdateID: lastUpdateID}
+	if bidQuantity != "" {
+		event.Bids = []binance.Bid{{Price: "60000", Quantity: bidQuantity}}
+	}
+	if askQuantity != "" {
+		event.Asks = []binance.Ask{{Price: "60010", Quantity: askQuantity}}
+	}
+	return event
+}
+
+// testMarketData returns market data watching BTCUSDT, whose depth snapshot is
+// one BTC on each side at update 100
+func testMarketData(t *testing.T) *binanceMarketData {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/depth" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"lastUpdateId":100,"bids":[["60000","1"]],"asks":[["60010","1"]]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := binance.NewClient("", "")
+	client.BaseURL = server.URL
+	m := newBinanceMarketData(client)
+	m.books["BTCUSDT"] = newLocalBook()
+	return m
+}
+
+func TestLocalBookApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        *binance.WsDepthEvent
+		wantErr      bool
+		wantUpdateID int64
+		wantBid      string
+	}{
+		{
+			name:         "stale diff",
+			event:        testDepthEvent(90, 100, "5", ""),
+			wantUpdateID: 100,
+			wantBid:      "1",
+		},
+		{
+			name:         "diff bridging the last update",
+			event:        testDepthEvent(95, 105, "5", ""),
+			wantUpdateID: 105,
+			wantBid:      "5",
+		},
+		{
+			name:         "next diff",
+			event:        testDepthEvent(101, 101, "5", ""),
+			wantUpdateID: 101,
+			wantBid:      "5",
+		},
+		{
+			name:         "diff removing the level",
+			event:        testDepthEvent(101, 101, "0", ""),
+			wantUpdateID: 101,
+		},
+		{
+			name:         "gap",
+			event:        testDepthEvent(102, 103, "5", ""),
+			wantErr:      true,
+			wantUpdateID: 100,
+			wantBid:      "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := newLocalBook()
+			book.lastUpdateID = 100
+			applyLevels(book.bids, []PriceLevel{{Price: sdk.NewDec(60000), Quantity: sdk.NewDec(1)}})
+
+			if err := book.apply(tt.event); tt.wantErr != (err != nil) {
+				t.Fatalf("apply() = %v, want an error: %t", err, tt.wantErr)
+			}
+			if book.lastUpdateID != tt.wantUpdateID {
+				t.Errorf("last update id = %d, want %d", book.lastUpdateID, tt.wantUpdateID)
+			}
+			checkLevel(t, book.bids, "60000", tt.wantBid)
+		})
+	}
+}
+
+func TestBinanceMarketDataResync(t *testing.T) {
+	tests := []struct {
+		name string
+		// buffered are the diffs received before the snapshot, live the ones after it
+		buffered     []*binance.WsDepthEvent
+		live         []*binance.WsDepthEvent
+		wantErr      bool
+		wantSynced   bool
+		wantResync   bool
+		wantUpdateID int64
+		wantBid      string
+		wantAsk      string
+	}{
+		{
+			name:         "stale diff dropped before the snapshot",
+			buffered:     []*binance.WsDepthEvent{testDepthEvent(90, 100, "5", ""), testDepthEvent(101, 102, "", "2")},
+			wantSynced:   true,
+			wantUpdateID: 102,
+			wantBid:      "1",
+			wantAsk:      "2",
+		},
+		{
+			name:         "first diff bridging the snapshot",
+			buffered:     []*binance.WsDepthEvent{testDepthEvent(95, 105, "3", "")},
+			wantSynced:   true,
+			wantUpdateID: 105,
+			wantBid:      "3",
+			wantAsk:      "1",
+		},
+		{
+			name:     "gap after the snapshot",
+			buffered: []*binance.WsDepthEvent{testDepthEvent(105, 110, "3", "")},
+			wantErr:  true,
+		},
+		{
+			name:       "gap on a synced book",
+			buffered:   []*binance.WsDepthEvent{testDepthEvent(101, 101, "3", "")},
+			live:       []*binance.WsDepthEvent{testDepthEvent(102, 102, "4", ""), testDepthEvent(105, 106, "5", "")},
+			wantResync: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMarketData(t)
+			for _, event := range tt.buffered {
+				m.onDepth(event)
+			}
+			if len(m.resync) != 1 {
+				t.Fatalf("queued %d resyncs for the unsynced book, want 1", len(m.resync))
+			}
+			<-m.resync
+
+			if err := m.resyncBook("BTCUSDT"); tt.wantErr != (err != nil) {
+				t.Fatalf("resyncBook() = %v, want an error: %t", err, tt.wantErr)
+			}
+			book := m.books["BTCUSDT"]
+			book.resyncing = false
+
+			for _, event := range tt.live {
+				m.onDepth(event)
+			}
+
+			if book.synced != tt.wantSynced {
+				t.Errorf("synced = %t, want %t", book.synced, tt.wantSynced)
+			}
+			if resync := len(m.resync) == 1; resync != tt.wantResync {
+				t.Errorf("resync queued = %t, want %t", resync, tt.wantResync)
+			}
+			if !tt.wantSynced {
+				return
+			}
+			if book.lastUpdateID != tt.wantUpdateID {
+				t.Errorf("last update id = %d, want %d", book.lastUpdateID, tt.wantUpdateID)
+			}
+			checkLevel(t, book.bids, "60000", tt.wantBid)
+			checkLevel(t, book.asks, "60010", tt.wantAsk)
+		})
+	}
+}
+
+// checkLevel checks the quantity at price on a side of a book, none when want is empty
+func checkLevel(t *testing.T, side map[string]PriceLevel, price, want string) {
+	t.Helper()
+	level, ok := side[sdk.MustNewDecFromStr(price).String()]
+	switch {
+	case want == "" && ok:
+		t.Errorf("level %s has %s, want none", price, level.Quantity)
+	case want != "" && !ok:
+		t.Errorf("level %s is missing, want %s", price, want)
+	case want != "" && !level.Quantity.Equal(sdk.MustNewDecFromStr(want)):
+		t.Errorf("level %s has %s, want %s", price, level.Quantity, want)
+	}
+}
@@ -0,0 +1,107 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// binance withdrawal statuses, see the withdraw history endpoint
+const (
+	binanceWithdrawCancelled = 1
+	binanceWithdrawRejected  = 3
+	binanceWithdrawFailure   = 5
+	binanceWithdrawCompleted = 6
+)
+
+// binance deposit statuses, see the deposit history endpoint
+const (
+	binanceDepositSuccess  = 1
+	binanceDepositCredited = 6
+	binanceDepositWrong    = 7
+)
+
+func (b *BinanceVenue) Withdraw(request WithdrawRequest) error {
+	defer observeLatency("binance_withdraw", time.Now())
+
+	service := b.client.NewCreateWithdrawService().
+		Coin(request.Asset).
+		Network(request.Network).
+		Address(request.Address).
+		Amount(formatDec(request.Amount, binanceQuantityDecimals)).
+		WithdrawOrderID(request.ID)
+	if request.Memo != "" {
+		service = service.AddressTag(request.Memo)
+	}
+
+	res, err := service.Do(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Println("Binance withdrawal", request.ID, "of", request.Amount, request.Asset, "accepted as", res.ID)
+	return nil
+}
+
+func (b *BinanceVenue) GetWithdrawal(asset, id string) (TransferStatus, string, error) {
+	defer observeLatency("binance_withdraw_history", time.Now())
+
+	withdrawals, err := b.client.NewListWithdrawsService().Coin(asset).WithdrawOrderId(id).Do(context.Background())
+	if err != nil {
+		return "", "", err
+	}
+	if len(withdrawals) == 0 {
+		return "", "", fmt.Errorf("%w: binance has no withdrawal %s", ErrTransferNotFound, id)
+	}
+
+	withdrawal := withdrawals[0]
+	switch withdrawal.Status {
+	case binanceWithdrawCompleted:
+		return TransferStatusCompleted, withdrawal.TxID, nil
+	case binanceWithdrawCancelled, binanceWithdrawRejected, binanceWithdrawFailure:
+		return TransferStatusFailed, withdrawal.TxID, nil
+	}
+	return TransferStatusPending, withdrawal.TxID, nil
+}
+
+func (b *BinanceVenue) GetDepositAddress(asset, network string) (string, string, error) {
+	defer observeLatency("binance_deposit_address", time.Now())
+
+	res, err := b.client.NewGetDepositAddressService().Coin(asset).Network(network).Do(context.Background())
+	if err != nil {
+		return "", "", err
+	}
+	return res.Address, res.Tag, nil
+}
+
+func (b *BinanceVenue) FindDeposit(asset, address, memo string, amount sdk.Dec, since time.Time) (TransferStatus, bool, error) {
+	defer observeLatency("binance_deposit_history", time.Now())
+
+	deposits, err := b.client.NewListDepositsService().
+		Coin(asset).
+		StartTime(since.UnixMilli()).
+		Do(context.Background())
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, deposit := range deposits {
+		if deposit.Address != address || deposit.AddressTag != memo {
+			continue
+		}
+		depositAmount, err := sdk.NewDecFromStr(deposit.Amount)
+		if err != nil || !depositAmount.Equal(amount) {
+			continue
+		}
+
+		switch deposit.Status {
+		case binanceDepositSuccess, binanceDepositCredited:
+			return TransferStatusCompleted, true, nil
+		case binanceDepositWrong:
+			return TransferStatusFailed, true, nil
+		}
+		return TransferStatusPending, true, nil
+	}
+	return "", false, nil
+}
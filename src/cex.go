@@ -0,0 +1,482 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// CEXVenue is a centralized exchange the bot can hedge its Osmosis trades on.
+// Binance is the production implementation, FakeVenue is an in-memory one
+// that can be used to exercise the arb logic without network access.
+type CEXVenue interface {
+	// Name returns a human readable name of the venue, used for logging
+	Name() string
+
+	// GetPrice returns the last traded price of the given symbol
+	GetPrice(symbol string) (sdk.Dec, error)
+
+	// GetDepth returns up to limit levels of each side of the order book
+	GetDepth(symbol string, limit int) (OrderBook, error)
+
+	// GetSymbolFilters returns the constraints orders on symbol have to meet
+	GetSymbolFilters(symbol string) (SymbolFilters, error)
+
+	// GetTradingFees returns the commission rates the account pays on symbol
+	GetTradingFees(symbol string) (TradingFees, error)
+
+	// GetBalances returns the free and locked balance of each requested asset.
+	// Assets the account does not hold are returned with a zero balance.
+	GetBalances(assets []string) (map[string]Balance, error)
+
+	// PlaceOrder submits an order and returns its state right after submission
+	PlaceOrder(order OrderRequest) (OrderResult, error)
+
+	// CancelOrder cancels an open order, orderID may also be the order's client order id
+	CancelOrder(symbol, orderID string) error
+
+	// GetOrderStatus returns the current state of a previously placed order.
+	// orderID may also be the order's client order id, unknown orders return ErrOrderNotFound.
+	GetOrderStatus(symbol, orderID string) (OrderResult, error)
+}
+
+// ErrOrderNotFound is returned for orders the venue has no record of
+var ErrOrderNotFound = errors.New("order not found")
+
+// findOrder looks up an order of symbol by its id or client order id in the
+// orders kept by in-memory venues
+func findOrder(orders map[string]OrderResult, symbol, orderID string) (OrderResult, error) {
+	if order, ok := orders[orderID]; ok && order.Symbol == symbol {
+		return order, nil
+	}
+	for _, order := range orders {
+		if order.ClientOrderID != "" && order.ClientOrderID == orderID && order.Symbol == symbol {
+			return order, nil
+		}
+	}
+	return OrderResult{}, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+}
+
+// BookTickerWatcher is implemented by venues that can push top of book changes,
+// letting the bot evaluate arbs as soon as the CEX price moves
+type BookTickerWatcher interface {
+	WatchBookTicker(ctx context.Context, symbols []string, onUpdate func(symbol string))
+}
+
+// AccountWatcher is implemented by venues that can push balance and order
+// updates, saving the API calls that would poll them
+type AccountWatcher interface {
+	WatchAccount(ctx context.Context)
+}
+
+// OrderWatcher is implemented by venues that can push order updates, letting
+// the bot react to fills as they happen instead of polling the order
+type OrderWatcher interface {
+	// WaitForOrder blocks until orderID is no longer resting on the book or ctx is
+	// done and returns its latest state. ok is false when the venue can't tell.
+	WaitForOrder(ctx context.Context, symbol, orderID string) (order OrderResult, ok bool)
+}
+
+// TransferVenue is implemented by venues funds can be withdrawn from and
+// deposited to, letting the rebalancer move inventory to and from Osmosis
+type TransferVenue interface {
+	// Withdraw sends a withdrawal, request.ID is the client id it can be looked up by
+	Withdraw(request WithdrawRequest) error
+
+	// GetWithdrawal returns the state of the withdrawal with the given client id
+	// and the hash of its on-chain tx once it has one. Unknown withdrawals return
+	// ErrTransferNotFound.
+	GetWithdrawal(asset, id string) (status TransferStatus, txHash string, err error)
+
+	// GetDepositAddress returns the address, and memo if the network needs one,
+	// deposits of asset over network are credited to
+	GetDepositAddress(asset, network string) (address, memo string, err error)
+
+	// FindDeposit returns the state of a deposit of amount of asset to address
+	// and memo made since the given time, found is false until the venue sees it
+	FindDeposit(asset, address, memo string, amount sdk.Dec, since time.Time) (status TransferStatus, found bool, err error)
+}
+
+// ErrTransferNotFound is returned for withdrawals the venue has no record of
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// WithdrawRequest withdraws Amount of Asset, in human readable units, over
+// Network to Address
+type WithdrawRequest struct {
+	ID      string
+	Asset   string
+	Network string
+	Address string
+	Memo    string
+	Amount  sdk.Dec
+}
+
+// TransferStatus is the state of a transfer of funds between venues
+type TransferStatus string
+
+const (
+	TransferStatusPending   TransferStatus = "pending"
+	TransferStatusCompleted TransferStatus = "completed"
+	TransferStatusFailed    TransferStatus = "failed"
+)
+
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "BUY"
+	OrderSideSell OrderSide = "SELL"
+)
+
+// OrderType is how an order executes against the book
+type OrderType string
+
+const (
+	// OrderTypeMarket fills the whole quantity at whatever the book offers
+	OrderTypeMarket OrderType = "market"
+	// OrderTypeLimitIOC fills what it can up to its price and cancels the rest
+	OrderTypeLimitIOC OrderType = "limit_ioc"
+	// OrderTypeLimitMaker rests on the book at its price and is rejected if it would cross
+	OrderTypeLimitMaker OrderType = "limit_maker"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusCanceled        OrderStatus = "CANCELED"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
+)
+
+// OrderRequest is an order for Quantity units of the symbol's base asset.
+// Price is the limit price of limit orders and is ignored for market orders.
+// ClientOrderID is optional and lets the order be looked up before its id is known.
+type OrderRequest struct {
+	Symbol        string
+	Side          OrderSide
+	Type          OrderType
+	Quantity      sdk.Dec
+	Price         sdk.Dec
+	ClientOrderID string
+}
+
+type OrderResult struct {
+	OrderID          string
+	ClientOrderID    string
+	Symbol           string
+	Side             OrderSide
+	Status           OrderStatus
+	ExecutedQuantity sdk.Dec
+	// Price is the volume weighted average price of the fills so far, zero without fills
+	Price sdk.Dec
+
+	// Commissions are the fees charged for the fills so far, keyed by asset
+	Commissions map[string]sdk.Dec
+}
+
+// PriceLevel is a single level of an order book, in human readable units
+type PriceLevel struct {
+	Price    sdk.Dec
+	Quantity sdk.Dec
+}
+
+// OrderBook holds bids sorted by descending price and asks sorted by ascending price
+type OrderBook struct {
+	Bids []PriceLevel
+	Asks []PriceLevel
+}
+
+// MidPrice returns the average of the best bid and best ask
+func (b OrderBook) MidPrice() (sdk.Dec, error) {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return sdk.Dec{}, fmt.Errorf("order book has an empty side")
+	}
+	return b.Bids[0].Price.Add(b.Asks[0].Price).QuoInt64(2), nil
+}
+
+// ExecutionPrice walks the book and returns the volume weighted average price a
+// market order for quantity would fill at. Buys consume asks, sells consume bids.
+// The price is rounded against us, up for buys and down for sells.
+func (b OrderBook) ExecutionPrice(side OrderSide, quantity sdk.Dec) (sdk.Dec, error) {
+	if !quantity.IsPositive() {
+		return sdk.Dec{}, fmt.Errorf("invalid quantity %s", quantity)
+	}
+
+	var levels []PriceLevel
+	switch side {
+	case OrderSideBuy:
+		levels = b.Asks
+	case OrderSideSell:
+		levels = b.Bids
+	default:
+		return sdk.Dec{}, fmt.Errorf("invalid order side %s", side)
+	}
+
+	remaining := quantity
+	notional := sdk.ZeroDec()
+	for _, level := range levels {
+		filled := sdk.MinDec(remaining, level.Quantity)
+		notional = notional.Add(filled.Mul(level.Price))
+		remaining = remaining.Sub(filled)
+		if !remaining.IsPositive() {
+			if side == OrderSideBuy {
+				return notional.QuoRoundUp(quantity), nil
+			}
+			return notional.QuoTruncate(quantity), nil
+		}
+	}
+
+	return sdk.Dec{}, fmt.Errorf("order book too thin to %s %s, %s left unfilled", side, quantity, remaining)
+}
+
+// Fill simulates order against the book and returns the quantity filled and its
+// average price, rounded like ExecutionPrice. Market orders have to fill in full,
+// IOC limit orders fill the levels up to their price and maker orders rest
+// without filling, erroring if they would cross the book.
+func (b OrderBook) Fill(order OrderRequest) (filled, price sdk.Dec, err error) {
+	switch order.Type {
+	case OrderTypeMarket, "":
+		price, err = b.ExecutionPrice(order.Side, order.Quantity)
+		if err != nil {
+			return sdk.Dec{}, sdk.Dec{}, err
+		}
+		return order.Quantity, price, nil
+	case OrderTypeLimitIOC, OrderTypeLimitMaker:
+	default:
+		return sdk.Dec{}, sdk.Dec{}, fmt.Errorf("invalid order type %s", order.Type)
+	}
+	if !order.Quantity.IsPositive() {
+		return sdk.Dec{}, sdk.Dec{}, fmt.Errorf("invalid quantity %s", order.Quantity)
+	}
+	if order.Price.IsNil() || !order.Price.IsPositive() {
+		return sdk.Dec{}, sdk.Dec{}, fmt.Errorf("invalid limit price %s", order.Price)
+	}
+
+	var levels []PriceLevel
+	switch order.Side {
+	case OrderSideBuy:
+		levels = b.Asks
+	case OrderSideSell:
+		levels = b.Bids
+	default:
+		return sdk.Dec{}, sdk.Dec{}, fmt.Errorf("invalid order side %s", order.Side)
+	}
+
+	filled, notional := sdk.ZeroDec(), sdk.ZeroDec()
+	for _, level := range levels {
+		if order.Side == OrderSideBuy && level.Price.GT(order.Price) ||
+			order.Side == OrderSideSell && level.Price.LT(order.Price) {
+			break
+		}
+		if order.Type == OrderTypeLimitMaker {
+			return sdk.Dec{}, sdk.Dec{}, fmt.Errorf("maker order at %s would cross the book", order.Price)
+		}
+
+		levelFilled := sdk.MinDec(order.Quantity.Sub(filled), level.Quantity)
+		notional = notional.Add(levelFilled.Mul(level.Price))
+		filled = filled.Add(levelFilled)
+		if filled.GTE(order.Quantity) {
+			break
+		}
+	}
+
+	switch {
+	case !filled.IsPositive():
+		return sdk.ZeroDec(), sdk.ZeroDec(), nil
+	case order.Side == OrderSideBuy:
+		return filled, notional.QuoRoundUp(filled), nil
+	default:
+		return filled, notional.QuoTruncate(filled), nil
+	}
+}
+
+// TradingFees are the commission rates of an account, as a share of the notional
+type TradingFees struct {
+	Maker sdk.Dec
+	Taker sdk.Dec
+	// Discount is the share of the commission waived when it is paid in the
+	// venue's own token, BNB on Binance. Zero when that is not the case.
+	Discount sdk.Dec
+}
+
+// Rate returns the commission rate an order of orderType pays after the
+// discount. Only maker orders are charged the maker rate.
+func (f TradingFees) Rate(orderType OrderType) sdk.Dec {
+	rate := f.Taker
+	if orderType == OrderTypeLimitMaker {
+		rate = f.Maker
+	}
+	if !isSetDec(rate) {
+		return sdk.ZeroDec()
+	}
+	if isSetDec(f.Discount) {
+		rate = rate.Mul(sdk.OneDec().Sub(f.Discount))
+	}
+	return rate
+}
+
+// SymbolFilters are the quantity, price and notional constraints of a symbol's
+// orders. Unset or zero values leave the order unconstrained.
+type SymbolFilters struct {
+	MinQuantity sdk.Dec
+	MaxQuantity sdk.Dec
+	StepSize    sdk.Dec
+
+	// MarketMinQuantity, MarketMaxQuantity and MarketStepSize bound market
+	// orders on top of the lot size above
+	MarketMinQuantity sdk.Dec
+	MarketMaxQuantity sdk.Dec
+	MarketStepSize    sdk.Dec
+
+	MinPrice sdk.Dec
+	MaxPrice sdk.Dec
+	TickSize sdk.Dec
+
+	// MinNotional and MaxNotional always bound limit orders, market orders only
+	// when the matching ApplyToMarket flag is set
+	MinNotional              sdk.Dec
+	MaxNotional              sdk.Dec
+	MinNotionalAppliesMarket bool
+	MaxNotionalAppliesMarket bool
+}
+
+// ForOrderType returns the filters that bound an order of orderType. Market
+// orders get the stricter of the two lot sizes and drop the notional bounds they
+// are exempt from, other orders drop the market lot size.
+func (f SymbolFilters) ForOrderType(orderType OrderType) SymbolFilters {
+	market := SymbolFilters{MinQuantity: f.MarketMinQuantity, MaxQuantity: f.MarketMaxQuantity, StepSize: f.MarketStepSize}
+	f.MarketMinQuantity, f.MarketMaxQuantity, f.MarketStepSize = sdk.Dec{}, sdk.Dec{}, sdk.Dec{}
+	if orderType != OrderTypeMarket {
+		return f
+	}
+
+	if isSetDec(market.MinQuantity) && (!isSetDec(f.MinQuantity) || market.MinQuantity.GT(f.MinQuantity)) {
+		f.MinQuantity = market.MinQuantity
+	}
+	if isSetDec(market.MaxQuantity) && (!isSetDec(f.MaxQuantity) || market.MaxQuantity.LT(f.MaxQuantity)) {
+		f.MaxQuantity = market.MaxQuantity
+	}
+	if isSetDec(market.StepSize) && (!isSetDec(f.StepSize) || market.StepSize.GT(f.StepSize)) {
+		f.StepSize = market.StepSize
+	}
+	if !f.MinNotionalAppliesMarket {
+		f.MinNotional = sdk.Dec{}
+	}
+	if !f.MaxNotionalAppliesMarket {
+		f.MaxNotional = sdk.Dec{}
+	}
+	return f
+}
+
+// RoundQuantity rounds quantity down to the step size, capped at the max
+// quantity. Call it on ForOrderType's filters to round for an order type.
+func (f SymbolFilters) RoundQuantity(quantity sdk.Dec) sdk.Dec {
+	if isSetDec(f.MaxQuantity) {
+		quantity = sdk.MinDec(quantity, f.MaxQuantity)
+	}
+	return roundDownToStep(quantity, f.StepSize)
+}
+
+// RoundPrice rounds price to the tick size so the order never trades beyond it,
+// down for buys and up for sells
+func (f SymbolFilters) RoundPrice(side OrderSide, price sdk.Dec) sdk.Dec {
+	if !isSetDec(f.TickSize) {
+		return price
+	}
+	rounded := roundDownToStep(price, f.TickSize)
+	if side == OrderSideSell && rounded.LT(price) {
+		rounded = rounded.Add(f.TickSize)
+	}
+	return rounded
+}
+
+// CheckOrder returns an error if an order of quantity at price would be
+// rejected. Quantity has to be on the step size, see RoundQuantity.
+func (f SymbolFilters) CheckOrder(quantity, price sdk.Dec) error {
+	if err := f.CheckQuantity(quantity); err != nil {
+		return err
+	}
+
+	if isSetDec(f.MinPrice) && price.LT(f.MinPrice) {
+		return fmt.Errorf("price %s is below the minimum of %s", price, f.MinPrice)
+	}
+	if isSetDec(f.MaxPrice) && price.GT(f.MaxPrice) {
+		return fmt.Errorf("price %s is above the maximum of %s", price, f.MaxPrice)
+	}
+
+	notional := quantity.Mul(price)
+	if isSetDec(f.MinNotional) && notional.LT(f.MinNotional) {
+		return fmt.Errorf("notional %s is below the minimum of %s", notional, f.MinNotional)
+	}
+	if isSetDec(f.MaxNotional) && notional.GT(f.MaxNotional) {
+		return fmt.Errorf("notional %s is above the maximum of %s", notional, f.MaxNotional)
+	}
+	return nil
+}
+
+// BelowMinimum returns whether an order for quantity at price is too small to be accepted
+func (f SymbolFilters) BelowMinimum(quantity, price sdk.Dec) bool {
+	if !quantity.IsPositive() {
+		return true
+	}
+	if isSetDec(f.MinQuantity) && quantity.LT(f.MinQuantity) {
+		return true
+	}
+	return isSetDec(f.MinNotional) && quantity.Mul(price).LT(f.MinNotional)
+}
+
+// CheckQuantity returns an error if quantity breaks the lot size filters
+func (f SymbolFilters) CheckQuantity(quantity sdk.Dec) error {
+	if !quantity.IsPositive() {
+		return fmt.Errorf("invalid quantity %s", quantity)
+	}
+	if isSetDec(f.MinQuantity) && quantity.LT(f.MinQuantity) {
+		return fmt.Errorf("quantity %s is below the minimum of %s", quantity, f.MinQuantity)
+	}
+	if isSetDec(f.MaxQuantity) && quantity.GT(f.MaxQuantity) {
+		return fmt.Errorf("quantity %s is above the maximum of %s", quantity, f.MaxQuantity)
+	}
+	if !roundDownToStep(quantity, f.StepSize).Equal(quantity) {
+		return fmt.Errorf("quantity %s is not a multiple of the step size %s", quantity, f.StepSize)
+	}
+	return nil
+}
+
+func isSetDec(d sdk.Dec) bool {
+	return !d.IsNil() && d.IsPositive()
+}
+
+// roundDownToStep rounds d down to a multiple of step, d is returned as is when step is unset
+func roundDownToStep(d, step sdk.Dec) sdk.Dec {
+	if !isSetDec(step) {
+		return d
+	}
+	return d.QuoTruncate(step).TruncateDec().Mul(step)
+}
+
+// filledOrderStatus is the status of an order that filled filled of its quantity
+// on submission, an IOC remainder is expired while a maker order keeps resting
+func filledOrderStatus(order OrderRequest, filled sdk.Dec) OrderStatus {
+	switch {
+	case filled.GTE(order.Quantity):
+		return OrderStatusFilled
+	case order.Type == OrderTypeLimitMaker:
+		return OrderStatusNew
+	default:
+		return OrderStatusExpired
+	}
+}
+
+// filterPrice is the price order is checked against the symbol filters at, its
+// limit price or, for market orders, the price it fills at
+func filterPrice(order OrderRequest, fillPrice sdk.Dec) sdk.Dec {
+	if order.Type == OrderTypeMarket || order.Type == "" {
+		return fillPrice
+	}
+	return order.Price
+}
@@ -0,0 +1,261 @@
+package src
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// FakeVenue is an in-memory CEXVenue. Orders fill immediately as OrderBook.Fill
+// does, against the symbol's order book if one is set and at its configured
+// price otherwise, and move the account balances accordingly. Fills pay the
+// trading fee rate of their order type in the asset received, as Binance charges
+// it. Maker orders rest until canceled.
+type FakeVenue struct {
+	mu sync.Mutex
+
+	markets  map[string]fakeMarket
+	balances map[string]sdk.Dec
+	orders   map[string]OrderResult
+	nextID   int64
+	fees     TradingFees
+}
+
+type fakeMarket struct {
+	baseAsset  string
+	quoteAsset string
+	price      sdk.Dec
+	book       OrderBook
+	filters    SymbolFilters
+}
+
+var _ CEXVenue = (*FakeVenue)(nil)
+
+func NewFakeVenue() *FakeVenue {
+	return &FakeVenue{
+		markets:  make(map[string]fakeMarket),
+		balances: make(map[string]sdk.Dec),
+		orders:   make(map[string]OrderResult),
+	}
+}
+
+// SetMarket registers symbol as trading baseAsset against quoteAsset at price
+func (f *FakeVenue) SetMarket(symbol, baseAsset, quoteAsset string, price sdk.Dec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market := f.markets[symbol]
+	market.baseAsset = baseAsset
+	market.quoteAsset = quoteAsset
+	market.price = price
+	f.markets[symbol] = market
+}
+
+// SetOrderBook sets the book returned by GetDepth for a registered symbol
+func (f *FakeVenue) SetOrderBook(symbol string, book OrderBook) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market := f.markets[symbol]
+	market.book = book
+	f.markets[symbol] = market
+}
+
+// SetSymbolFilters sets the filters orders on a registered symbol are checked against
+func (f *FakeVenue) SetSymbolFilters(symbol string, filters SymbolFilters) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market := f.markets[symbol]
+	market.filters = filters
+	f.markets[symbol] = market
+}
+
+// SetTradingFees sets the commission rates of every symbol, they are zero by default
+func (f *FakeVenue) SetTradingFees(fees TradingFees) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fees = fees
+}
+
+func (f *FakeVenue) SetBalance(asset string, amount sdk.Dec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.balances[asset] = amount
+}
+
+func (f *FakeVenue) Name() string {
+	return "Fake"
+}
+
+func (f *FakeVenue) GetPrice(symbol string) (sdk.Dec, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market, ok := f.markets[symbol]
+	if !ok {
+		return sdk.Dec{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return market.price, nil
+}
+
+func (f *FakeVenue) GetDepth(symbol string, limit int) (OrderBook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market, ok := f.markets[symbol]
+	if !ok {
+		return OrderBook{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+
+	book := OrderBook{
+		Bids: append([]PriceLevel{}, market.book.Bids...),
+		Asks: append([]PriceLevel{}, market.book.Asks...),
+	}
+	if limit > 0 && len(book.Bids) > limit {
+		book.Bids = book.Bids[:limit]
+	}
+	if limit > 0 && len(book.Asks) > limit {
+		book.Asks = book.Asks[:limit]
+	}
+	return book, nil
+}
+
+func (f *FakeVenue) GetSymbolFilters(symbol string) (SymbolFilters, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market, ok := f.markets[symbol]
+	if !ok {
+		return SymbolFilters{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return market.filters, nil
+}
+
+func (f *FakeVenue) GetTradingFees(symbol string) (TradingFees, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.markets[symbol]; !ok {
+		return TradingFees{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return f.fees, nil
+}
+
+func (f *FakeVenue) GetBalances(assets []string) (map[string]Balance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	balances := make(map[string]Balance, len(assets))
+	for _, asset := range assets {
+		balances[asset] = NewBalance(f.balance(asset), sdk.ZeroDec())
+	}
+	return balances, nil
+}
+
+func (f *FakeVenue) PlaceOrder(order OrderRequest) (OrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	market, ok := f.markets[order.Symbol]
+	if !ok {
+		return OrderResult{}, fmt.Errorf("unknown symbol %s", order.Symbol)
+	}
+	if !order.Quantity.IsPositive() {
+		return OrderResult{}, fmt.Errorf("invalid order quantity %s", order.Quantity)
+	}
+
+	// fill against the book when one is set so fills match what GetDepth quoted
+	book := market.book
+	if len(book.Bids) == 0 && len(book.Asks) == 0 {
+		level := []PriceLevel{{Price: market.price, Quantity: order.Quantity}}
+		book = OrderBook{Bids: level, Asks: level}
+	}
+	filled, price, err := book.Fill(order)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	if err := market.filters.ForOrderType(order.Type).CheckOrder(order.Quantity, filterPrice(order, price)); err != nil {
+		return OrderResult{}, err
+	}
+
+	quoteAmount := filled.Mul(price)
+	feeRate := f.fees.Rate(order.Type)
+	commissions := make(map[string]sdk.Dec)
+	baseBalance, quoteBalance := f.balance(market.baseAsset), f.balance(market.quoteAsset)
+	switch order.Side {
+	case OrderSideBuy:
+		if quoteBalance.LT(quoteAmount) {
+			return OrderResult{}, fmt.Errorf("insufficient %s balance", market.quoteAsset)
+		}
+		commission := filled.Mul(feeRate)
+		f.balances[market.quoteAsset] = quoteBalance.Sub(quoteAmount)
+		f.balances[market.baseAsset] = baseBalance.Add(filled).Sub(commission)
+		if commission.IsPositive() {
+			commissions[market.baseAsset] = commission
+		}
+	case OrderSideSell:
+		if baseBalance.LT(filled) {
+			return OrderResult{}, fmt.Errorf("insufficient %s balance", market.baseAsset)
+		}
+		commission := quoteAmount.Mul(feeRate)
+		f.balances[market.baseAsset] = baseBalance.Sub(filled)
+		f.balances[market.quoteAsset] = quoteBalance.Add(quoteAmount).Sub(commission)
+		if commission.IsPositive() {
+			commissions[market.quoteAsset] = commission
+		}
+	default:
+		return OrderResult{}, fmt.Errorf("invalid order side %s", order.Side)
+	}
+
+	f.nextID++
+	result := OrderResult{
+		OrderID:          strconv.FormatInt(f.nextID, 10),
+		ClientOrderID:    order.ClientOrderID,
+		Symbol:           order.Symbol,
+		Side:             order.Side,
+		Status:           filledOrderStatus(order, filled),
+		ExecutedQuantity: filled,
+		Price:            price,
+		Commissions:      commissions,
+	}
+	f.orders[result.OrderID] = result
+
+	return result, nil
+}
+
+// balance returns the balance of asset, zero if it was never set. Must be called with mu held.
+func (f *FakeVenue) balance(asset string) sdk.Dec {
+	if balance, ok := f.balances[asset]; ok {
+		return balance
+	}
+	return sdk.ZeroDec()
+}
+
+func (f *FakeVenue) CancelOrder(symbol, orderID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order, err := findOrder(f.orders, symbol, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != OrderStatusNew && order.Status != OrderStatusPartiallyFilled {
+		return fmt.Errorf("order %s is already %s", orderID, order.Status)
+	}
+
+	order.Status = OrderStatusCanceled
+	f.orders[order.OrderID] = order
+	return nil
+}
+
+func (f *FakeVenue) GetOrderStatus(symbol, orderID string) (OrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return findOrder(f.orders, symbol, orderID)
+}
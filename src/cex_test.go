@@ -0,0 +1,84 @@
+package src
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestSymbolFiltersForOrderType(t *testing.T) {
+	filters := SymbolFilters{
+		MinQuantity:       sdk.MustNewDecFromStr("0.0001"),
+		MaxQuantity:       sdk.NewDec(9000),
+		StepSize:          sdk.MustNewDecFromStr("0.0001"),
+		MarketMinQuantity: sdk.MustNewDecFromStr("0.001"),
+		MarketMaxQuantity: sdk.NewDec(100),
+		MarketStepSize:    sdk.MustNewDecFromStr("0.001"),
+	}
+	// a market lot size Binance leaves at zero doesn't loosen the lot size
+	unsetMarket := filters
+	unsetMarket.MarketMinQuantity, unsetMarket.MarketMaxQuantity, unsetMarket.MarketStepSize = sdk.ZeroDec(), sdk.ZeroDec(), sdk.ZeroDec()
+
+	tests := []struct {
+		name      string
+		filters   SymbolFilters
+		orderType OrderType
+		quantity  sdk.Dec
+		want      sdk.Dec
+	}{
+		{
+			name:      "limit on the lot step",
+			filters:   filters,
+			orderType: OrderTypeLimitIOC,
+			quantity:  sdk.MustNewDecFromStr("0.12345"),
+			want:      sdk.MustNewDecFromStr("0.1234"),
+		},
+		{
+			name:      "limit above the market max",
+			filters:   filters,
+			orderType: OrderTypeLimitIOC,
+			quantity:  sdk.NewDec(500),
+			want:      sdk.NewDec(500),
+		},
+		{
+			name:      "market on the market step",
+			filters:   filters,
+			orderType: OrderTypeMarket,
+			quantity:  sdk.MustNewDecFromStr("0.12345"),
+			want:      sdk.MustNewDecFromStr("0.123"),
+		},
+		{
+			name:      "market capped at the market max",
+			filters:   filters,
+			orderType: OrderTypeMarket,
+			quantity:  sdk.NewDec(500),
+			want:      sdk.NewDec(100),
+		},
+		{
+			name:      "market below the market min",
+			filters:   filters,
+			orderType: OrderTypeMarket,
+			quantity:  sdk.MustNewDecFromStr("0.0005"),
+			want:      sdk.ZeroDec(),
+		},
+		{
+			name:      "market without a market lot size",
+			filters:   unsetMarket,
+			orderType: OrderTypeMarket,
+			quantity:  sdk.MustNewDecFromStr("0.12345"),
+			want:      sdk.MustNewDecFromStr("0.1234"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := tt.filters.ForOrderType(tt.orderType)
+			got := filters.RoundQuantity(tt.quantity)
+			if !got.Equal(tt.want) {
+				t.Errorf("RoundQuantity(%s) = %s, want %s", tt.quantity, got, tt.want)
+			}
+			if err := filters.CheckQuantity(got); got.IsPositive() && err != nil {
+				t.Errorf("CheckQuantity(%s) = %v, want nil", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// TradingPair describes a market that is arbitraged between Osmosis and the CEX.
+// Amounts and limits are in human readable units of the base asset.
+type TradingPair struct {
+	Name string `json:"name"`
+
+	BaseDenom     string `json:"base_denom"`
+	QuoteDenom    string `json:"quote_denom"`
+	BaseExponent  int    `json:"base_exponent"`
+	QuoteExponent int    `json:"quote_exponent"`
+
+	CEXSymbol     string `json:"cex_symbol"`
+	CEXBaseAsset  string `json:"cex_base_asset"`
+	CEXQuoteAsset string `json:"cex_quote_asset"`
+
+	// MinArbAmount is the smallest trade worth executing, MaxArbAmount caps the
+	// size of a single arb. A MaxArbAmount of 0 means no cap.
+	MinArbAmount float64 `json:"min_arb_amount"`
+	MaxArbAmount float64 `json:"max_arb_amount"`
+
+	// MaxNotional caps the quote value of a single arb, 0 means no cap
+	MaxNotional float64 `json:"max_notional"`
+
+	// ArbPercentage is the share of its free balance each venue spends on one arb
+	ArbPercentage float64 `json:"arb_percentage"`
+
+	// MinProfit and MinProfitBps are the net expected profit an arb has to make
+	// after fees, gas and the auction bid before it is traded, in units of the
+	// quote asset and in bps of the arb's notional
+	MinProfit    float64 `json:"min_profit"`
+	MinProfitBps float64 `json:"min_profit_bps"`
+
+	// MaxSlippageBps is how far below the quoted amount out an Osmosis swap may fill
+	MaxSlippageBps uint64 `json:"max_slippage_bps"`
+
+	// MaxQuoteDeviationBps is how far the quoted amount out may be from the
+	// node's own estimate of the route before the arb is rejected
+	MaxQuoteDeviationBps uint64 `json:"max_quote_deviation_bps"`
+
+	// AuctionBidFraction is the share of the expected profit bid for top of block,
+	// MaxAuctionBid caps the bid, in the smallest unit of the auction's bid denom
+	AuctionBidFraction float64 `json:"auction_bid_fraction"`
+	MaxAuctionBid      int64   `json:"max_auction_bid"`
+
+	// HedgeOrderType is the type of the CEX order hedging the Osmosis leg. Limit
+	// orders are priced at the Osmosis execution price, the worst the hedge can
+	// fill at without a loss.
+	HedgeOrderType OrderType `json:"hedge_order_type"`
+
+	// HedgeMaxAttempts is how many orders are sent to hedge an arb before giving
+	// up and swapping the unhedged part back on Osmosis. Attempts are spaced by
+	// HedgeRetryBackoffMs, doubling after every attempt.
+	HedgeMaxAttempts    int   `json:"hedge_max_attempts"`
+	HedgeRetryBackoffMs int64 `json:"hedge_retry_backoff_ms"`
+
+	// UnwindMaxAttempts is how many times the unhedged part is swapped back before
+	// the arb is given up on. MaxUnwindLossBps is how far below the Osmosis leg's
+	// own price the unwind may fill.
+	UnwindMaxAttempts int    `json:"unwind_max_attempts"`
+	MaxUnwindLossBps  uint64 `json:"max_unwind_loss_bps"`
+}
+
+type ArbConfig struct {
+	Pairs []TradingPair `json:"pairs"`
+
+	// DebounceMs is how long triggers are collected before an arb is evaluated
+	DebounceMs int64 `json:"debounce_ms"`
+
+	// BlockPollIntervalMs is how often the node is polled for new blocks
+	BlockPollIntervalMs int64 `json:"block_poll_interval_ms"`
+
+	// MarketDataStaleMs is how far the CEX depth stream may lag before its book
+	// is not used anymore
+	MarketDataStaleMs int64 `json:"market_data_stale_ms"`
+
+	// RouteIntermediateDenoms are the denoms the local router's two hop routes may
+	// go through, by default the fee denom and the quote denoms of the pairs
+	RouteIntermediateDenoms []string `json:"route_intermediate_denoms"`
+
+	// Rebalance moves inventory between the CEX and Osmosis, it is disabled without assets
+	Rebalance RebalanceConfig `json:"rebalance"`
+}
+
+// RebalanceConfig sets how inventory is kept balanced between the CEX and Osmosis
+type RebalanceConfig struct {
+	// IntervalMs is how often inventory is checked and transfers are tracked
+	IntervalMs int64            `json:"interval_ms"`
+	Assets     []RebalanceAsset `json:"assets"`
+}
+
+// RebalanceAsset is an asset held on both venues. Amounts are in human readable units.
+type RebalanceAsset struct {
+	CEXAsset string `json:"cex_asset"`
+	Denom    string `json:"denom"`
+	Exponent int    `json:"exponent"`
+
+	// OsmosisShare is the share of the asset's total inventory to hold on Osmosis.
+	// A transfer is made once the share on Osmosis drifts more than Threshold away from it.
+	OsmosisShare float64 `json:"osmosis_share"`
+	Threshold    float64 `json:"threshold"`
+	// MinTransfer is the smallest transfer worth making
+	MinTransfer float64 `json:"min_transfer"`
+
+	// WithdrawNetwork is the CEX network withdrawals to the Osmosis account are sent over
+	WithdrawNetwork string `json:"withdraw_network"`
+	// DepositNetwork is the CEX network deposits are credited over, reached from
+	// Osmosis by an IBC transfer over IBCChannel
+	DepositNetwork string `json:"deposit_network"`
+	IBCChannel     string `json:"ibc_channel"`
+}
+
+// DefaultBTCUSDCPair is the pair the bot trades when no config file is given:
+// Osmosis' WBTC/USDC against Binance's BTCUSDT. The quote assets differ, Osmosis
+// USDC is counted as Binance USDT at par and its balance is reported as USDT, so
+// the arb's profit is only as good as the two stablecoins' peg.
+var DefaultBTCUSDCPair = TradingPair{
+	Name:           "BTC/USDC",
+	BaseDenom:      "factory/osmo1z0qrq605sjgcqpylfl4aa6s90x738j7m58wyatt0tdzflg2ha26q67k743/wbtc",
+	QuoteDenom:     "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4",
+	BaseExponent:   unsetExponent,
+	QuoteExponent:  unsetExponent,
+	CEXSymbol:      "BTCUSDT",
+	CEXBaseAsset:   "BTC",
+	CEXQuoteAsset:  "USDT",
+	MinArbAmount:   0.0001,
+	ArbPercentage:  defaultArbPercentage,
+	MinProfitBps:   defaultMinProfitBps,
+	MaxSlippageBps: defaultMaxSlippageBps,
+
+	MaxQuoteDeviationBps: defaultMaxQuoteDeviationBps,
+
+	AuctionBidFraction: defaultAuctionBidFraction,
+	MaxAuctionBid:      defaultMaxAuctionBid,
+
+	HedgeOrderType:      OrderTypeMarket,
+	HedgeMaxAttempts:    defaultHedgeMaxAttempts,
+	HedgeRetryBackoffMs: defaultHedgeRetryBackoffMs,
+
+	UnwindMaxAttempts: defaultUnwindMaxAttempts,
+	MaxUnwindLossBps:  defaultMaxUnwindLossBps,
+}
+
+// defaultArbConfig is the config before the config file is applied, trading only DefaultBTCUSDCPair
+func defaultArbConfig() ArbConfig {
+	return ArbConfig{
+		Pairs:               []TradingPair{DefaultBTCUSDCPair},
+		DebounceMs:          defaultDebounceMs,
+		BlockPollIntervalMs: defaultBlockPollIntervalMs,
+		MarketDataStaleMs:   defaultMarketDataStaleMs,
+		Rebalance:           RebalanceConfig{IntervalMs: defaultRebalanceIntervalMs},
+	}
+}
+
+// unsetExponent marks an exponent left out of the config, resolved from the denom registry
+const unsetExponent = -1
+
+// UnmarshalJSON decodes a pair, leaving exponents that are not set unsetExponent.
+// Fields left out take their default, a field set to 0 stays 0.
+func (p *TradingPair) UnmarshalJSON(bz []byte) error {
+	type tradingPair TradingPair
+	pair := tradingPair{
+		BaseExponent:         unsetExponent,
+		QuoteExponent:        unsetExponent,
+		ArbPercentage:        defaultArbPercentage,
+		MinProfitBps:         defaultMinProfitBps,
+		MaxSlippageBps:       defaultMaxSlippageBps,
+		MaxQuoteDeviationBps: defaultMaxQuoteDeviationBps,
+		AuctionBidFraction:   defaultAuctionBidFraction,
+		MaxAuctionBid:        defaultMaxAuctionBid,
+		HedgeOrderType:       OrderTypeMarket,
+		HedgeMaxAttempts:     defaultHedgeMaxAttempts,
+		HedgeRetryBackoffMs:  defaultHedgeRetryBackoffMs,
+		UnwindMaxAttempts:    defaultUnwindMaxAttempts,
+		MaxUnwindLossBps:     defaultMaxUnwindLossBps,
+	}
+	if err := json.Unmarshal(bz, &pair); err != nil {
+		return err
+	}
+	*p = TradingPair(pair)
+	return nil
+}
+
+// UnmarshalJSON decodes an asset, leaving its exponent unsetExponent if it is not
+// set. Fields left out take their default, a field set to 0 stays 0.
+func (a *RebalanceAsset) UnmarshalJSON(bz []byte) error {
+	type rebalanceAsset RebalanceAsset
+	asset := rebalanceAsset{
+		Exponent:        unsetExponent,
+		OsmosisShare:    defaultRebalanceOsmosisShare,
+		Threshold:       defaultRebalanceThreshold,
+		WithdrawNetwork: defaultRebalanceWithdrawNetwork,
+	}
+	if err := json.Unmarshal(bz, &asset); err != nil {
+		return err
+	}
+	*a = RebalanceAsset(asset)
+	return nil
+}
+
+// LoadArbConfig reads the config file pointed to by ARB_CONFIG_PATH.
+// When the variable is unset only the default BTC/USDC pair is traded.
+// Settings left out of the file take their default, ones set to 0 are kept 0.
+// Exponents left out of the config are resolved from seedConfig's denom registry.
+func LoadArbConfig(seedConfig SeedConfig) (ArbConfig, error) {
+	config := defaultArbConfig()
+
+	path := os.Getenv("ARB_CONFIG_PATH")
+	if path != "" {
+		bz, err := os.ReadFile(path)
+		if err != nil {
+			return ArbConfig{}, fmt.Errorf("error reading arb config: %v", err)
+		}
+
+		config.Pairs = nil
+		if err := json.Unmarshal(bz, &config); err != nil {
+			return ArbConfig{}, fmt.Errorf("error decoding arb config: %v", err)
+		}
+
+		if len(config.Pairs) == 0 {
+			return ArbConfig{}, fmt.Errorf("arb config %s has no pairs", path)
+		}
+	}
+
+	if config.DebounceMs < 0 || config.MarketDataStaleMs < 0 {
+		return ArbConfig{}, fmt.Errorf("debounce and market data staleness must not be negative")
+	}
+	if config.BlockPollIntervalMs <= 0 {
+		return ArbConfig{}, fmt.Errorf("block poll interval must be positive")
+	}
+
+	// the bundled asset list still resolves denoms when the node could not be reached
+	registry := seedConfig.Denoms
+	if registry == nil {
+		var err error
+		registry, err = NewDenomRegistry(nil)
+		if err != nil {
+			return ArbConfig{}, err
+		}
+	}
+
+	for i := range config.Pairs {
+		pair := &config.Pairs[i]
+		if err := ResolvePairDenoms(seedConfig, registry, pair); err != nil {
+			return ArbConfig{}, err
+		}
+		if err := pair.Validate(); err != nil {
+			return ArbConfig{}, err
+		}
+	}
+
+	if len(config.RouteIntermediateDenoms) == 0 {
+		config.RouteIntermediateDenoms = []string{FEE_DENOM}
+		for _, pair := range config.Pairs {
+			if !slices.Contains(config.RouteIntermediateDenoms, pair.QuoteDenom) {
+				config.RouteIntermediateDenoms = append(config.RouteIntermediateDenoms, pair.QuoteDenom)
+			}
+		}
+	}
+
+	if len(config.Rebalance.Assets) > 0 && config.Rebalance.IntervalMs <= 0 {
+		return ArbConfig{}, fmt.Errorf("rebalance interval must be positive")
+	}
+	for i := range config.Rebalance.Assets {
+		asset := &config.Rebalance.Assets[i]
+		if asset.Denom != "" {
+			info, err := registry.Resolve(asset.Denom)
+			switch {
+			case err != nil && asset.Exponent == unsetExponent:
+				return ArbConfig{}, fmt.Errorf("rebalance asset %s: %v", asset.CEXAsset, err)
+			case err != nil:
+				// the configured exponent is used for denoms the registry can't resolve
+			case asset.Exponent == unsetExponent:
+				asset.Exponent = info.Exponent
+			case asset.Exponent != info.Exponent:
+				return ArbConfig{}, fmt.Errorf("rebalance asset %s configures an exponent of %d but the registry has %d", asset.CEXAsset, asset.Exponent, info.Exponent)
+			}
+		}
+		if err := asset.Validate(); err != nil {
+			return ArbConfig{}, err
+		}
+	}
+
+	return config, nil
+}
+
+func (c RebalanceConfig) Interval() time.Duration {
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+func (a RebalanceAsset) Validate() error {
+	if a.CEXAsset == "" || a.Denom == "" {
+		return fmt.Errorf("rebalance asset is missing its cex asset or denom")
+	}
+	if a.Exponent < 0 {
+		return fmt.Errorf("rebalance asset %s: exponent must not be negative", a.CEXAsset)
+	}
+	if a.OsmosisShare <= 0 || a.OsmosisShare >= 1 {
+		return fmt.Errorf("rebalance asset %s: osmosis share must be in (0, 1)", a.CEXAsset)
+	}
+	if a.Threshold <= 0 || a.Threshold >= 1 {
+		return fmt.Errorf("rebalance asset %s: threshold must be in (0, 1)", a.CEXAsset)
+	}
+	if a.MinTransfer < 0 {
+		return fmt.Errorf("rebalance asset %s: min transfer must not be negative", a.CEXAsset)
+	}
+	if a.DepositNetwork == "" || a.IBCChannel == "" {
+		return fmt.Errorf("rebalance asset %s: deposit network and ibc channel are required", a.CEXAsset)
+	}
+	return nil
+}
+
+func (c ArbConfig) Debounce() time.Duration {
+	return time.Duration(c.DebounceMs) * time.Millisecond
+}
+
+func (c ArbConfig) BlockPollInterval() time.Duration {
+	return time.Duration(c.BlockPollIntervalMs) * time.Millisecond
+}
+
+func (c ArbConfig) MarketDataStaleAfter() time.Duration {
+	return time.Duration(c.MarketDataStaleMs) * time.Millisecond
+}
+
+// Pair returns the configured pair with the given name
+func (c ArbConfig) Pair(name string) (TradingPair, bool) {
+	for _, pair := range c.Pairs {
+		if pair.Name == name {
+			return pair, true
+		}
+	}
+	return TradingPair{}, false
+}
+
+// CEXSymbols returns the CEX symbol of every configured pair
+func (c ArbConfig) CEXSymbols() []string {
+	symbols := make([]string, len(c.Pairs))
+	for i, pair := range c.Pairs {
+		symbols[i] = pair.CEXSymbol
+	}
+	return symbols
+}
+
+func (p TradingPair) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("trading pair is missing a name")
+	}
+	if p.BaseDenom == "" || p.QuoteDenom == "" {
+		return fmt.Errorf("trading pair %s: base and quote denoms are required", p.Name)
+	}
+	if p.BaseExponent < 0 || p.QuoteExponent < 0 {
+		return fmt.Errorf("trading pair %s: exponents must not be negative", p.Name)
+	}
+	if p.CEXSymbol == "" || p.CEXBaseAsset == "" || p.CEXQuoteAsset == "" {
+		return fmt.Errorf("trading pair %s: cex symbol and assets are required", p.Name)
+	}
+	if p.MinArbAmount < 0 || p.MaxArbAmount < 0 || p.MaxNotional < 0 {
+		return fmt.Errorf("trading pair %s: arb amount limits must not be negative", p.Name)
+	}
+	if p.MaxArbAmount != 0 && p.MaxArbAmount < p.MinArbAmount {
+		return fmt.Errorf("trading pair %s: max arb amount is below min arb amount", p.Name)
+	}
+	if p.ArbPercentage <= 0 || p.ArbPercentage > 1 {
+		return fmt.Errorf("trading pair %s: arb percentage must be in (0, 1]", p.Name)
+	}
+	if p.MinProfit < 0 || p.MinProfitBps < 0 {
+		return fmt.Errorf("trading pair %s: min profit must not be negative", p.Name)
+	}
+	if p.MaxSlippageBps >= 10000 {
+		return fmt.Errorf("trading pair %s: max slippage must be below 10000 bps", p.Name)
+	}
+	if p.MaxQuoteDeviationBps >= 10000 {
+		return fmt.Errorf("trading pair %s: max quote deviation must be below 10000 bps", p.Name)
+	}
+	if p.AuctionBidFraction <= 0 || p.AuctionBidFraction > 1 {
+		return fmt.Errorf("trading pair %s: auction bid fraction must be in (0, 1]", p.Name)
+	}
+	if p.MaxAuctionBid < 0 {
+		return fmt.Errorf("trading pair %s: max auction bid must not be negative", p.Name)
+	}
+	switch p.HedgeOrderType {
+	case OrderTypeMarket, OrderTypeLimitIOC, OrderTypeLimitMaker:
+	default:
+		return fmt.Errorf("trading pair %s: invalid hedge order type %s", p.Name, p.HedgeOrderType)
+	}
+	if p.HedgeMaxAttempts < 1 {
+		return fmt.Errorf("trading pair %s: hedge max attempts must be at least 1", p.Name)
+	}
+	if p.HedgeRetryBackoffMs < 0 {
+		return fmt.Errorf("trading pair %s: hedge retry backoff must not be negative", p.Name)
+	}
+	if p.UnwindMaxAttempts < 1 {
+		return fmt.Errorf("trading pair %s: unwind max attempts must be at least 1", p.Name)
+	}
+	if p.MaxUnwindLossBps >= 10000 {
+		return fmt.Errorf("trading pair %s: max unwind loss must be below 10000 bps", p.Name)
+	}
+	return nil
+}
+
+// HedgeRetryBackoff returns how long to wait before the given hedge attempt,
+// doubling from HedgeRetryBackoffMs after every attempt up to hedgeRetryMaxBackoff
+func (p TradingPair) HedgeRetryBackoff(attempt int) time.Duration {
+	backoff := time.Duration(p.HedgeRetryBackoffMs) * time.Millisecond
+	for i := 1; i < attempt && backoff < hedgeRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > hedgeRetryMaxBackoff {
+		return hedgeRetryMaxBackoff
+	}
+	return backoff
+}
@@ -1,18 +1,88 @@
 package src
 
+import "time"
+
 const (
 	osmosisQuoteAPI = "https://sqs.osmosis.zone/router/quote"
-	osmosisRouteAPI = "https://sqs.osmosis.zone/router/routes"
 
-	BTCDenom  = "factory/osmo1z0qrq605sjgcqpylfl4aa6s90x738j7m58wyatt0tdzflg2ha26q67k743/wbtc"
-	USDCDenom = "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4"
-	BidDenom  = "stake"
+	cexDepthLimit = 100
+
+	// binanceQuantityDecimals is the most decimals Binance accepts in an order quantity
+	binanceQuantityDecimals = 8
+	binancePriceDecimals    = 8
+	binanceSymbolFiltersTTL = time.Hour
+	binanceTradingFeesTTL   = 10 * time.Minute
+	// binanceBNBFeeDiscount is the share of spot commissions waived when they are paid in binanceFeeAsset
+	binanceFeeAsset       = "BNB"
+	binanceBNBFeeDiscount = "0.25"
+	// binanceUnknownOrderCode and binanceCancelRejectedCode are the API errors for orders Binance has no record of
+	binanceUnknownOrderCode   = -2013
+	binanceCancelRejectedCode = -2011
+	// binanceExecutionNew and binanceExecutionTrade are the execution report types of a new order and of a fill
+	binanceExecutionNew   = "NEW"
+	binanceExecutionTrade = "TRADE"
+
+	defaultArbPercentage = 0.1
+	defaultMinProfitBps  = 10
+	// arbSizingSteps is how many sizes up to the largest fundable one an arb is priced at
+	arbSizingSteps = 5
+
+	defaultMaxSlippageBps       = 50
+	defaultMaxQuoteDeviationBps = 10
+
+	defaultAuctionBidFraction = 0.2
+	defaultMaxAuctionBid      = 10_000_000
+
+	defaultHedgeMaxAttempts    = 3
+	defaultHedgeRetryBackoffMs = 500
+	hedgeRetryMaxBackoff       = 30 * time.Second
+
+	defaultUnwindMaxAttempts = 3
+	defaultMaxUnwindLossBps  = 200
+
+	defaultRebalanceIntervalMs      = 60_000
+	defaultRebalanceOsmosisShare    = 0.5
+	defaultRebalanceThreshold       = 0.2
+	defaultRebalanceWithdrawNetwork = "OSMO"
+	ibcTransferTimeout              = 10 * time.Minute
+	// transferDepositTimeout is how long a transfer to the CEX may go without being credited before it is given up on
+	transferDepositTimeout = 2 * time.Hour
+	// transferLookupGrace is how long a withdrawal may be missing from the CEX history before it is considered never made
+	transferLookupGrace = time.Minute
+
+	defaultDebounceMs          = 250
+	defaultBlockPollIntervalMs = 1000
+	streamReconnectDelay       = 5 * time.Second
+
+	defaultMarketDataStaleMs    = 2000
+	binanceMarketDataStaleAfter = defaultMarketDataStaleMs * time.Millisecond
+	binanceDepthSnapshotLimit   = 1000
+	binanceResyncDelay          = time.Second
+	// listen keys expire after 60 minutes without a keepalive
+	binanceListenKeyKeepalive = 30 * time.Minute
+	// binanceClosedOrderTTL is how long a closed order nobody read is kept from the user data stream
+	binanceClosedOrderTTL = 10 * time.Minute
+	txConfirmPollInterval = 500 * time.Millisecond
+
+	// binanceWeightLimit and the order limits are Binance's published spot limits,
+	// the bot only uses binanceRateLimitShare of each
+	binanceWeightLimit       = 6000
+	binanceOrderLimit10s     = 100
+	binanceOrderLimit1d      = 200_000
+	binanceRateLimitShare    = 0.8
+	binanceIPBannedStatus    = 418
+	binanceDefaultRetryAfter = time.Minute
 
-	binanceBTCUSDTTicker = "BTCUSDT"
+	defaultJournalPath = "arb_journal"
+	journalArbPrefix   = "arb/"
+	// journalInFlightPrefix indexes the journal keys of arbs that have not reached a final state
+	journalInFlightPrefix = "inflight/"
+	journalTransferPrefix = "transfer/"
+	// journalPendingTransferPrefix indexes the journal keys of transfers that have not landed yet
+	journalPendingTransferPrefix = "pending-transfer/"
 
-	defaultArbAmt = 0.0001
-	riskFactor    = 0.98
+	defaultMetricsAddress = ":9090"
 
-	osmosisWBTCExponent = 8
-	osmosisUSDCExponent = 6
+	localRouterMaxSplitRoutes = 3
+	localRouterSplitSteps     = 10
 )
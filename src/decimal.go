@@ -0,0 +1,60 @@
+package src
+
+import (
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// floatToDec converts a config value to a decimal, dropping digits past sdk.Dec's precision
+func floatToDec(f float64) sdk.Dec {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > sdk.Precision {
+		s = s[:i+1+sdk.Precision]
+	}
+	return sdk.MustNewDecFromStr(s)
+}
+
+// decToFloat converts a decimal for reporting only, nil decimals are reported as 0
+func decToFloat(d sdk.Dec) float64 {
+	if d.IsNil() {
+		return 0
+	}
+	return d.MustFloat64()
+}
+
+// toBaseUnits converts a human readable amount to the smallest unit of a denom
+// with the given exponent, rounding down so no more than amount is ever spent
+func toBaseUnits(amount sdk.Dec, exponent int) sdk.Int {
+	return amount.Mul(exponentMultiplier(exponent)).TruncateInt()
+}
+
+// toBaseUnitsRoundUp is toBaseUnits rounding up, for minimum amounts to receive
+func toBaseUnitsRoundUp(amount sdk.Dec, exponent int) sdk.Int {
+	return amount.Mul(exponentMultiplier(exponent)).Ceil().TruncateInt()
+}
+
+// fromBaseUnits converts an amount in the smallest unit of a denom with the
+// given exponent to human readable units
+func fromBaseUnits(amount sdk.Int, exponent int) sdk.Dec {
+	return sdk.NewDecFromInt(amount).Quo(exponentMultiplier(exponent))
+}
+
+// truncateDec rounds d down to the given number of decimals
+func truncateDec(d sdk.Dec, decimals int) sdk.Dec {
+	return fromBaseUnits(toBaseUnits(d, decimals), decimals)
+}
+
+// formatDec formats d truncated to the given number of decimals, without trailing zeros
+func formatDec(d sdk.Dec, decimals int) string {
+	s := truncateDec(d, decimals).String()
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func exponentMultiplier(exponent int) sdk.Dec {
+	return sdk.NewDec(10).Power(uint64(exponent))
+}
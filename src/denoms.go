@@ -0,0 +1,225 @@
+package src
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	transfertypes "github.com/cosmos/ibc-go/v7/modules/apps/transfer/types"
+	"google.golang.org/grpc"
+)
+
+//go:embed assetlist.json
+var bundledAssetList []byte
+
+// DenomInfo describes how a denom is displayed
+type DenomInfo struct {
+	Denom  string
+	Symbol string
+	// Exponent is the number of decimals between the denom and its display unit
+	Exponent int
+	// Path is the IBC trace of the denom, e.g. transfer/channel-0/uatom, empty for native denoms
+	Path string
+}
+
+// DenomRegistry resolves denoms from the chain's bank metadata, falling back to
+// the bundled Osmosis asset list. IBC denoms missing from both are traced back
+// to their origin and matched against the asset list by trace path.
+type DenomRegistry struct {
+	bankClient     banktypes.QueryClient
+	transferClient transfertypes.QueryClient
+	assets         []assetListAsset
+
+	mu     sync.Mutex
+	denoms map[string]DenomInfo
+}
+
+type assetList struct {
+	Assets []assetListAsset `json:"assets"`
+}
+
+type assetListAsset struct {
+	Base       string `json:"base"`
+	Symbol     string `json:"symbol"`
+	Display    string `json:"display"`
+	DenomUnits []struct {
+		Denom    string `json:"denom"`
+		Exponent int    `json:"exponent"`
+	} `json:"denom_units"`
+	Traces []struct {
+		Type  string `json:"type"`
+		Chain struct {
+			Path string `json:"path"`
+		} `json:"chain"`
+	} `json:"traces"`
+}
+
+// NewDenomRegistry creates a registry querying the node at conn. When conn is nil
+// only the bundled asset list is used.
+func NewDenomRegistry(conn *grpc.ClientConn) (*DenomRegistry, error) {
+	var list assetList
+	if err := json.Unmarshal(bundledAssetList, &list); err != nil {
+		return nil, fmt.Errorf("error decoding bundled asset list: %v", err)
+	}
+
+	registry := &DenomRegistry{
+		assets: list.Assets,
+		denoms: make(map[string]DenomInfo),
+	}
+	if conn != nil {
+		registry.bankClient = banktypes.NewQueryClient(conn)
+		registry.transferClient = transfertypes.NewQueryClient(conn)
+	}
+	return registry, nil
+}
+
+// Resolve returns the display info of denom, caching it for later lookups
+func (r *DenomRegistry) Resolve(denom string) (DenomInfo, error) {
+	r.mu.Lock()
+	info, ok := r.denoms[denom]
+	r.mu.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	info, err := r.resolve(denom)
+	if err != nil {
+		return DenomInfo{}, err
+	}
+
+	r.mu.Lock()
+	r.denoms[denom] = info
+	r.mu.Unlock()
+	return info, nil
+}
+
+func (r *DenomRegistry) resolve(denom string) (DenomInfo, error) {
+	if info, ok := r.bankMetadata(denom); ok {
+		return info, nil
+	}
+	if asset, ok := r.assetByBase(denom); ok {
+		return asset.info(denom)
+	}
+	if !strings.HasPrefix(denom, "ibc/") {
+		return DenomInfo{}, fmt.Errorf("denom %s has no bank metadata and is not in the asset list", denom)
+	}
+
+	trace, err := r.denomTrace(denom)
+	if err != nil {
+		return DenomInfo{}, fmt.Errorf("error tracing %s: %v", denom, err)
+	}
+	path := trace.GetFullDenomPath()
+	if asset, ok := r.assetByTracePath(path); ok {
+		info, err := asset.info(denom)
+		info.Path = path
+		return info, err
+	}
+	return DenomInfo{}, fmt.Errorf("denom %s (%s) has no bank metadata and is not in the asset list", denom, path)
+}
+
+// bankMetadata resolves denom from its x/bank metadata, if the chain has any
+func (r *DenomRegistry) bankMetadata(denom string) (DenomInfo, bool) {
+	if r.bankClient == nil {
+		return DenomInfo{}, false
+	}
+
+	res, err := r.bankClient.DenomMetadata(context.Background(), &banktypes.QueryDenomMetadataRequest{Denom: denom})
+	if err != nil {
+		log.Println("No bank metadata for", denom, ":", err)
+		return DenomInfo{}, false
+	}
+
+	metadata := res.Metadata
+	for _, unit := range metadata.DenomUnits {
+		if unit.Denom != metadata.Display {
+			continue
+		}
+		symbol := metadata.Symbol
+		if symbol == "" {
+			symbol = strings.ToUpper(metadata.Display)
+		}
+		return DenomInfo{Denom: denom, Symbol: symbol, Exponent: int(unit.Exponent)}, true
+	}
+
+	log.Println("Bank metadata of", denom, "has no display unit")
+	return DenomInfo{}, false
+}
+
+func (r *DenomRegistry) denomTrace(denom string) (transfertypes.DenomTrace, error) {
+	if r.transferClient == nil {
+		return transfertypes.DenomTrace{}, fmt.Errorf("no node to query")
+	}
+
+	res, err := r.transferClient.DenomTrace(context.Background(), &transfertypes.QueryDenomTraceRequest{Hash: denom})
+	if err != nil {
+		return transfertypes.DenomTrace{}, err
+	}
+	return *res.DenomTrace, nil
+}
+
+func (r *DenomRegistry) assetByBase(denom string) (assetListAsset, bool) {
+	for _, asset := range r.assets {
+		if asset.Base == denom {
+			return asset, true
+		}
+	}
+	return assetListAsset{}, false
+}
+
+func (r *DenomRegistry) assetByTracePath(path string) (assetListAsset, bool) {
+	for _, asset := range r.assets {
+		for _, trace := range asset.Traces {
+			if trace.Chain.Path == path {
+				return asset, true
+			}
+		}
+	}
+	return assetListAsset{}, false
+}
+
+func (a assetListAsset) info(denom string) (DenomInfo, error) {
+	for _, unit := range a.DenomUnits {
+		if unit.Denom == a.Display {
+			return DenomInfo{Denom: denom, Symbol: a.Symbol, Exponent: unit.Exponent}, nil
+		}
+	}
+	return DenomInfo{}, fmt.Errorf("asset list entry of %s has no display unit", a.Base)
+}
+
+// ResolvePairDenoms fills the exponents left out of pair's config from the denom
+// registry and records the symbol of its denoms in seedConfig.DenomMap.
+// A configured exponent that disagrees with the registry is an error, as it would
+// scale every amount of the denom by a power of ten. Configured exponents are
+// only used as is for denoms the registry can't resolve.
+func ResolvePairDenoms(seedConfig SeedConfig, registry *DenomRegistry, pair *TradingPair) error {
+	resolve := func(denom string, exponent *int) error {
+		info, err := registry.Resolve(denom)
+		if err != nil {
+			if *exponent == unsetExponent {
+				return fmt.Errorf("trading pair %s: %v", pair.Name, err)
+			}
+			log.Println("Using the configured exponent of", denom, ":", err)
+			return nil
+		}
+
+		if seedConfig.DenomMap != nil {
+			seedConfig.DenomMap[denom] = info.Symbol
+		}
+		if *exponent == unsetExponent {
+			*exponent = info.Exponent
+		} else if *exponent != info.Exponent {
+			return fmt.Errorf("trading pair %s configures an exponent of %d for %s but the registry has %d", pair.Name, *exponent, info.Symbol, info.Exponent)
+		}
+		return nil
+	}
+
+	if err := resolve(pair.BaseDenom, &pair.BaseExponent); err != nil {
+		return err
+	}
+	return resolve(pair.QuoteDenom, &pair.QuoteExponent)
+}
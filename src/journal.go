@@ -0,0 +1,311 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type ArbDirection string
+
+const (
+	// ArbDirectionBuyCEX buys base on the CEX and sells it on Osmosis
+	ArbDirectionBuyCEX ArbDirection = "buy_cex_sell_osmosis"
+	// ArbDirectionSellCEX sells base on the CEX and buys it on Osmosis
+	ArbDirectionSellCEX ArbDirection = "sell_cex_buy_osmosis"
+)
+
+// ArbRecord is the journal entry of a single arb opportunity and its execution.
+// Prices and amounts are in human readable units, PnL is in units of the pair's quote.
+type ArbRecord struct {
+	ID        string       `json:"id"`
+	Time      time.Time    `json:"time"`
+	Pair      string       `json:"pair"`
+	Direction ArbDirection `json:"direction"`
+	ArbAmount float64      `json:"arb_amount"`
+	// State is the step the arb's execution has reached
+	State ArbState `json:"state,omitempty"`
+
+	CEXBuyPrice      float64 `json:"cex_buy_price"`
+	CEXSellPrice     float64 `json:"cex_sell_price"`
+	OsmosisBuyPrice  float64 `json:"osmosis_buy_price"`
+	OsmosisSellPrice float64 `json:"osmosis_sell_price"`
+	ExpectedProfit   float64 `json:"expected_profit"`
+
+	// the expected profit's breakdown, ExpectedProfit is ExpectedGross less the rest
+	ExpectedGross      float64 `json:"expected_gross"`
+	ExpectedCEXFee     float64 `json:"expected_cex_fee"`
+	ExpectedPoolFees   float64 `json:"expected_pool_fees"`
+	ExpectedGas        float64 `json:"expected_gas"`
+	ExpectedAuctionBid float64 `json:"expected_auction_bid"`
+
+	OsmosisTxHash   string   `json:"osmosis_tx_hash,omitempty"`
+	OsmosisTxStatus TxStatus `json:"osmosis_tx_status,omitempty"`
+	// OsmosisTimeoutHeight is the height after which the swap can no longer be included
+	OsmosisTimeoutHeight uint64 `json:"osmosis_timeout_height,omitempty"`
+	// OsmosisIn and OsmosisOut are the exact coins the swap spent and returned
+	OsmosisIn       sdk.Coin `json:"osmosis_in"`
+	OsmosisOut      sdk.Coin `json:"osmosis_out"`
+	OsmosisTokenIn  float64  `json:"osmosis_token_in"`
+	OsmosisTokenOut float64  `json:"osmosis_token_out"`
+	AuctionBid      string   `json:"auction_bid,omitempty"`
+	GasUsed         int64    `json:"gas_used"`
+	TxFees          string   `json:"tx_fees,omitempty"`
+	// OsmosisCosts are the tx fees and auction bid, valued in quote
+	OsmosisCosts float64 `json:"osmosis_costs"`
+
+	CEXOrderID        string      `json:"cex_order_id,omitempty"`
+	CEXOrderType      OrderType   `json:"cex_order_type,omitempty"`
+	CEXOrderStatus    OrderStatus `json:"cex_order_status,omitempty"`
+	CEXLimitPrice     float64     `json:"cex_limit_price,omitempty"`
+	CEXFilledQuantity float64     `json:"cex_filled_quantity"`
+	CEXFillPrice      float64     `json:"cex_fill_price"`
+	// CEXCommissions are the commissions of all fills, keyed by asset
+	CEXCommissions map[string]float64 `json:"cex_commissions,omitempty"`
+	// Hedge is the exact progress of the CEX leg across all of its orders
+	Hedge HedgeProgress `json:"hedge"`
+
+	// the Osmosis swap reversing the part of the arb the hedge did not cover
+	UnwindTxHash        string   `json:"unwind_tx_hash,omitempty"`
+	UnwindTxStatus      TxStatus `json:"unwind_tx_status,omitempty"`
+	UnwindTimeoutHeight uint64   `json:"unwind_timeout_height,omitempty"`
+	UnwindIn            sdk.Coin `json:"unwind_in"`
+	UnwindOut           sdk.Coin `json:"unwind_out"`
+	UnwindTokenIn       float64  `json:"unwind_token_in"`
+	UnwindTokenOut      float64  `json:"unwind_token_out"`
+	// UnwindAttempts is how many unwinds were tried, including ones that never went out
+	UnwindAttempts int `json:"unwind_attempts,omitempty"`
+
+	RealizedPnL float64 `json:"realized_pnl"`
+	// ResidualBase is the base the arb left on the account unhedged, in human
+	// readable units, negative when it spent more than it got back. It is
+	// inventory, not profit, and is kept out of RealizedPnL.
+	ResidualBase float64 `json:"residual_base"`
+	Error        string  `json:"error,omitempty"`
+	// SkipReason is why an opportunity was not traded, set with ArbStateSkipped
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+// Executed returns whether both legs of the arb went through, on paper in dry run
+func (r ArbRecord) Executed() bool {
+	executed := r.OsmosisTxStatus == TxStatusIncluded || r.OsmosisTxStatus == TxStatusSimulated
+	return executed && r.CEXFilledQuantity > 0
+}
+
+// CalculateRealizedPnL values the quote both legs moved in and out of the
+// account, net of fees. Base left over is recorded as ResidualBase instead.
+// The unwind swap, if any, counts towards the Osmosis leg.
+// cexCommissionInQuote is the CEX commission already converted to quote.
+func (r *ArbRecord) CalculateRealizedPnL(cexCommissionInQuote float64) {
+	cexQuote := r.CEXFilledQuantity * r.CEXFillPrice
+
+	var baseDelta, quoteDelta float64
+	switch r.Direction {
+	case ArbDirectionBuyCEX:
+		baseDelta = r.CEXFilledQuantity - r.OsmosisTokenIn + r.UnwindTokenOut
+		quoteDelta = r.OsmosisTokenOut - cexQuote - r.UnwindTokenIn
+	case ArbDirectionSellCEX:
+		baseDelta = r.OsmosisTokenOut - r.CEXFilledQuantity - r.UnwindTokenIn
+		quoteDelta = cexQuote - r.OsmosisTokenIn + r.UnwindTokenOut
+	}
+
+	r.ResidualBase = baseDelta
+	r.RealizedPnL = quoteDelta - cexCommissionInQuote - r.OsmosisCosts
+}
+
+// Journal persists arb records in an embedded LevelDB database
+type Journal struct {
+	db *leveldb.DB
+}
+
+// JournalPathFromEnv returns the journal directory set in JOURNAL_PATH, or the default one
+func JournalPathFromEnv() string {
+	if path := os.Getenv("JOURNAL_PATH"); path != "" {
+		return path
+	}
+	return defaultJournalPath
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal at %s: %v", path, err)
+	}
+	return &Journal{db: db}, nil
+}
+
+func (j *Journal) Close() error {
+	return j.db.Close()
+}
+
+// Record stores record, keyed so that records iterate in time order, replacing
+// any earlier version of it. Records of arbs in flight are indexed until they
+// reach a final state, and are synced to disk to survive a crash.
+func (j *Journal) Record(record ArbRecord) error {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	key := journalKey(record)
+	inFlightKey := []byte(journalInFlightPrefix + record.ID)
+
+	batch := new(leveldb.Batch)
+	batch.Put(key, bz)
+	if record.State.InFlight() {
+		batch.Put(inFlightKey, key)
+	} else {
+		batch.Delete(inFlightKey)
+	}
+	return j.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// InFlight returns the records of arbs that have not reached a final state, oldest first
+func (j *Journal) InFlight() ([]ArbRecord, error) {
+	iter := j.db.NewIterator(util.BytesPrefix([]byte(journalInFlightPrefix)), nil)
+	defer iter.Release()
+
+	var records []ArbRecord
+	for iter.Next() {
+		bz, err := j.db.Get(iter.Value(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error reading arb %s: %v", iter.Key(), err)
+		}
+
+		var record ArbRecord
+		if err := json.Unmarshal(bz, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, k int) bool { return records[i].Time.Before(records[k].Time) })
+	return records, nil
+}
+
+// Records returns the records of the given UTC day, oldest first
+func (j *Journal) Records(day time.Time) ([]ArbRecord, error) {
+	prefix := []byte(journalArbPrefix + day.UTC().Format("20060102"))
+
+	iter := j.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	var records []ArbRecord
+	for iter.Next() {
+		var record ArbRecord
+		if err := json.Unmarshal(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, iter.Error()
+}
+
+// RecordTransfer stores transfer, replacing any earlier version of it. Pending
+// transfers are indexed until they land or fail.
+func (j *Journal) RecordTransfer(transfer Transfer) error {
+	bz, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(journalTransferPrefix + transfer.Time.UTC().Format("20060102T150405.000000000") + "/" + transfer.ID)
+	pendingKey := []byte(journalPendingTransferPrefix + transfer.ID)
+
+	batch := new(leveldb.Batch)
+	batch.Put(key, bz)
+	if transfer.Status == TransferStatusPending {
+		batch.Put(pendingKey, key)
+	} else {
+		batch.Delete(pendingKey)
+	}
+	return j.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// PendingTransfers returns the transfers that have not landed or failed yet, oldest first
+func (j *Journal) PendingTransfers() ([]Transfer, error) {
+	iter := j.db.NewIterator(util.BytesPrefix([]byte(journalPendingTransferPrefix)), nil)
+	defer iter.Release()
+
+	var pending []Transfer
+	for iter.Next() {
+		bz, err := j.db.Get(iter.Value(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error reading transfer %s: %v", iter.Key(), err)
+		}
+
+		var transfer Transfer
+		if err := json.Unmarshal(bz, &transfer); err != nil {
+			return nil, err
+		}
+		pending = append(pending, transfer)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pending, func(i, k int) bool { return pending[i].Time.Before(pending[k].Time) })
+	return pending, nil
+}
+
+// PairReport sums up a pair's arbs over a day. Opportunities counts every
+// profitable evaluation journaled, Skipped the ones not traded.
+type PairReport struct {
+	Pair          string
+	Opportunities int
+	Skipped       int
+	Executed      int
+	Failed        int
+	Volume        float64
+	RealizedPnL   float64
+	ResidualBase  float64
+}
+
+// DailyReport returns a report per pair of the arbs of the given UTC day
+func (j *Journal) DailyReport(day time.Time) ([]PairReport, error) {
+	records, err := j.Records(day)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []PairReport
+	index := make(map[string]int)
+	for _, record := range records {
+		i, ok := index[record.Pair]
+		if !ok {
+			i = len(reports)
+			index[record.Pair] = i
+			reports = append(reports, PairReport{Pair: record.Pair})
+		}
+
+		report := &reports[i]
+		report.Opportunities++
+		if record.State == ArbStateSkipped {
+			report.Skipped++
+		} else if record.Executed() {
+			report.Executed++
+			report.Volume += record.CEXFilledQuantity
+		} else if record.Error != "" {
+			report.Failed++
+		}
+		report.RealizedPnL += record.RealizedPnL
+		report.ResidualBase += record.ResidualBase
+	}
+
+	return reports, nil
+}
+
+func journalKey(record ArbRecord) []byte {
+	return []byte(journalArbPrefix + record.Time.UTC().Format("20060102T150405.000000000") + "/" + record.ID)
+}
@@ -0,0 +1,130 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	tradeResultExecuted = "executed"
+	tradeResultFailed   = "failed"
+)
+
+var (
+	loopIterations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "arb_loop_iterations_total",
+		Help: "Number of arb evaluations across all pairs",
+	})
+	opportunities = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_opportunities_total",
+		Help: "Number of arb opportunities seen",
+	}, []string{"pair", "direction"})
+	trades = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_trades_total",
+		Help: "Number of arbs attempted, by result",
+	}, []string{"pair", "direction", "result"})
+	quoteRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_quote_rejections_total",
+		Help: "Number of Osmosis quotes rejected for deviating from the node's estimate",
+	}, []string{"pair"})
+	arbStates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_state_transitions_total",
+		Help: "Number of arbs entering each execution state",
+	}, []string{"pair", "state"})
+	inventoryShare = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_inventory_osmosis_share",
+		Help: "Share of an asset's inventory held on Osmosis",
+	}, []string{"asset"})
+	transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_transfers_total",
+		Help: "Number of rebalancing transfers between the CEX and Osmosis, by outcome",
+	}, []string{"asset", "direction", "status"})
+	priceSpread = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_price_spread_bps",
+		Help: "Spread between the CEX and Osmosis executable prices in the direction of the arb, in bps",
+	}, []string{"pair", "direction"})
+	marketDataLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_market_data_lag_seconds",
+		Help: "Time since the last CEX depth update applied to the local order book was sent",
+	}, []string{"symbol"})
+	orderBookResyncs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_order_book_resyncs_total",
+		Help: "Local CEX order books dropped and resynced from a snapshot, by reason",
+	}, []string{"symbol", "reason"})
+	rateLimitRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_binance_rate_limit_remaining",
+		Help: "Binance request weight or orders left in the bot's budget, by limit",
+	}, []string{"limit"})
+	rateLimitWaits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_binance_rate_limit_waits_total",
+		Help: "Binance requests held back until the budget refilled, by path",
+	}, []string{"path"})
+	rateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_binance_rate_limit_rejections_total",
+		Help: "Binance requests rejected for exceeding a rate limit, by status code",
+	}, []string{"status"})
+	userDataEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "arb_user_data_events_total",
+		Help: "CEX user data stream events received, by event type",
+	}, []string{"event"})
+	balances = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_balance",
+		Help: "Free balance held on each venue, in human readable units",
+	}, []string{"venue", "asset"})
+	lockedBalances = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_locked_balance",
+		Help: "Balance locked in open orders on each venue, in human readable units",
+	}, []string{"venue", "asset"})
+	auctionBid = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arb_auction_bid",
+		Help: "Last top of block auction bid paid, in the bid denom's smallest unit",
+	}, []string{"pair", "denom"})
+	gasUsed = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "arb_osmosis_gas_used",
+		Help:    "Gas used by Osmosis swap txs",
+		Buckets: prometheus.ExponentialBuckets(100_000, 2, 8),
+	}, []string{"pair"})
+	callLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "arb_call_duration_seconds",
+		Help:    "Latency of calls to SQS, the node, the CEX API and of tx confirmations",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"call"})
+)
+
+// observeLatency records the time since start under call, meant to be deferred
+func observeLatency(call string, start time.Time) {
+	callLatency.WithLabelValues(call).Observe(time.Since(start).Seconds())
+}
+
+// MetricsAddressFromEnv returns the listen address set in METRICS_ADDRESS, or the default one
+func MetricsAddressFromEnv() string {
+	if addr := os.Getenv("METRICS_ADDRESS"); addr != "" {
+		return addr
+	}
+	return defaultMetricsAddress
+}
+
+// ServeMetrics serves the prometheus metrics on /metrics until ctx is done
+func ServeMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Println("Serving metrics on", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("Error serving metrics:", err)
+	}
+}
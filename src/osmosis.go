@@ -2,11 +2,13 @@ package src
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
 	"math"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
@@ -14,32 +16,50 @@ import (
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gogoproto/proto"
 	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
 
 	poolmanagertypes "github.com/osmosis-labs/osmosis/v25/x/poolmanager/types"
+	txfeestypes "github.com/osmosis-labs/osmosis/v25/x/txfees/types"
 )
 
-// Note that the amount here should be in human readable exponent
-// E.g) getting usdc price of 1 bitcoin would be GetOsmosisBTCToUSDCPrice(1)
-func GetOsmosisBTCToUSDCPriceAndRoute(tokenInAmount float64) (float64, []poolmanagertypes.SwapAmountInSplitRoute, error) {
-	amountWithExponentApplied := int64(tokenInAmount * math.Pow(10, osmosisWBTCExponent))
+// OsmosisQuote is a priced route for swapping TokenInAmount of one denom into another
+type OsmosisQuote struct {
+	// Price is in human readable units of token out per token in, rounded down
+	Price          sdk.Dec
+	TokenInAmount  sdk.Int
+	TokenOutAmount sdk.Int
+	Route          []poolmanagertypes.SwapAmountInSplitRoute
+	// FeeRate is the share of the amount in the route's pools charge in spread
+	// factors and taker fees. TokenOutAmount is already net of it.
+	FeeRate sdk.Dec
+}
 
-	btcExecutionPrice, route, err := getOsmosisPriceAndRoute(BTCDenom, USDCDenom, amountWithExponentApplied)
-	if err != nil {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, err
-	}
+// Note that the amount here should be in human readable exponent, it is rounded
+// down to the base denom's smallest unit
+// E.g) getting usdc price of 1 bitcoin would be GetOsmosisBaseToQuoteQuote(seedConfig, btcPair, sdk.OneDec())
+func GetOsmosisBaseToQuoteQuote(seedConfig SeedConfig, pair TradingPair, tokenInAmount sdk.Dec) (OsmosisQuote, error) {
+	return getOsmosisPairQuote(seedConfig, pair.BaseDenom, pair.BaseExponent, pair.QuoteDenom, pair.QuoteExponent, tokenInAmount)
+}
 
-	return btcExecutionPrice * math.Pow(10, osmosisWBTCExponent-osmosisUSDCExponent), route, nil
+// GetOsmosisQuoteToBaseQuote returns a quote priced in base received per unit of quote
+func GetOsmosisQuoteToBaseQuote(seedConfig SeedConfig, pair TradingPair, tokenInAmount sdk.Dec) (OsmosisQuote, error) {
+	return getOsmosisPairQuote(seedConfig, pair.QuoteDenom, pair.QuoteExponent, pair.BaseDenom, pair.BaseExponent, tokenInAmount)
 }
 
-func GetOsmosisUSDCToBTCPriceAndRoute(tokenInAmount float64) (float64, []poolmanagertypes.SwapAmountInSplitRoute, error) {
-	amountWithExponentApplied := int64(tokenInAmount * math.Pow(10, osmosisUSDCExponent))
+func getOsmosisPairQuote(seedConfig SeedConfig, tokenInDenom string, tokenInExponent int, tokenOutDenom string, tokenOutExponent int, tokenInAmount sdk.Dec) (OsmosisQuote, error) {
+	amountWithExponentApplied := toBaseUnits(tokenInAmount, tokenInExponent)
+	if !amountWithExponentApplied.IsPositive() {
+		return OsmosisQuote{}, fmt.Errorf("amount in %s rounds down to zero %s", tokenInAmount, tokenInDenom)
+	}
 
-	usdcPrice, route, err := getOsmosisPriceAndRoute(USDCDenom, BTCDenom, amountWithExponentApplied)
+	quote, err := getOsmosisPriceAndRoute(seedConfig, tokenInDenom, tokenOutDenom, amountWithExponentApplied)
 	if err != nil {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, err
+		return OsmosisQuote{}, err
 	}
-	return usdcPrice / math.Pow(10, osmosisWBTCExponent-osmosisUSDCExponent), route, nil
+
+	quote.Price = fromBaseUnits(quote.TokenOutAmount, tokenOutExponent).QuoTruncate(fromBaseUnits(quote.TokenInAmount, tokenInExponent))
+	return quote, nil
 }
 
 type QuoteResponse struct {
@@ -55,109 +75,397 @@ type Route struct {
 type Pool struct {
 	PoolId        uint64 `json:"id"`
 	TokenOutdenom string `json:"token_out_denom"`
+	SpreadFactor  string `json:"spread_factor"`
+	TakerFee      string `json:"taker_fee"`
 }
 
-func getOsmosisPriceAndRoute(tokenInDenom, tokenOutDenom string, tokenInAmount int64) (float64, []poolmanagertypes.SwapAmountInSplitRoute, error) {
-	url := fmt.Sprintf("%s?tokenIn=%d%s&tokenOutDenom=%s&humanDenoms=false", osmosisQuoteAPI, tokenInAmount, tokenInDenom, tokenOutDenom)
+// getOsmosisPriceAndRoute quotes a swap with the router selected in seedConfig
+func getOsmosisPriceAndRoute(seedConfig SeedConfig, tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error) {
+	return seedConfig.osmosis().Quote(tokenInDenom, tokenOutDenom, tokenInAmount)
+}
+
+func getSQSPriceAndRoute(tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error) {
+	defer observeLatency("sqs_quote", time.Now())
+
+	url := fmt.Sprintf("%s?tokenIn=%s%s&tokenOutDenom=%s&humanDenoms=false", osmosisQuoteAPI, tokenInAmount, tokenInDenom, tokenOutDenom)
 	resp, err := http.Get(url)
 	if err != nil {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, fmt.Errorf("error fetching price from Osmosis: %v", err)
+		return OsmosisQuote{}, fmt.Errorf("error fetching price from Osmosis: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, fmt.Errorf("error fetching price from Osmosis: status code %d", resp.StatusCode)
+		return OsmosisQuote{}, fmt.Errorf("error fetching price from Osmosis: status code %d", resp.StatusCode)
 	}
 
 	var quoteResponse QuoteResponse
 	err = json.NewDecoder(resp.Body).Decode(&quoteResponse)
 	if err != nil {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, fmt.Errorf("error decoding response: %v", err)
+		return OsmosisQuote{}, fmt.Errorf("error decoding response: %v", err)
 	}
 
 	// manually unmarshal struct returned from sqs to poolmanager type struct
 	// we can't directly unmarshal into pool manager's SwapAmountInSplitRoute
 	// due to json tag differences
 	route := make([]poolmanagertypes.SwapAmountInSplitRoute, len(quoteResponse.Route))
+	pathFeeRates := make([]sdk.Dec, len(quoteResponse.Route))
 
 	for i, quotedRoute := range quoteResponse.Route {
 		inAmount, ok := sdk.NewIntFromString(quotedRoute.InAmount)
 		if !ok {
-			return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, fmt.Errorf("error parsing in amount from endpoint")
+			return OsmosisQuote{}, fmt.Errorf("error parsing in amount from endpoint")
 		}
 		route[i].TokenInAmount = inAmount
 
 		// Initialize the Pools slice for the current route with the length of quotedRoute.Pools
 		route[i].Pools = make([]poolmanagertypes.SwapAmountInRoute, len(quotedRoute.Pools))
 
+		var hopFeeRates []sdk.Dec
 		for j, pool := range quotedRoute.Pools {
 			route[i].Pools[j].PoolId = pool.PoolId
 			route[i].Pools[j].TokenOutDenom = pool.TokenOutdenom
+
+			for _, rate := range []string{pool.TakerFee, pool.SpreadFactor} {
+				if rate == "" {
+					continue
+				}
+				feeRate, err := sdk.NewDecFromStr(rate)
+				if err != nil {
+					return OsmosisQuote{}, fmt.Errorf("error parsing pool %d fee %q: %v", pool.PoolId, rate, err)
+				}
+				hopFeeRates = append(hopFeeRates, feeRate)
+			}
 		}
+		pathFeeRates[i] = pathFeeRate(hopFeeRates)
 	}
 
-	amountOut, err := strconv.ParseFloat(quoteResponse.AmountOut, 64)
-	if err != nil {
-		return 0, []poolmanagertypes.SwapAmountInSplitRoute{}, fmt.Errorf("error parsing amount_out: %v", err)
+	amountOut, ok := sdk.NewIntFromString(quoteResponse.AmountOut)
+	if !ok {
+		return OsmosisQuote{}, fmt.Errorf("error parsing amount_out: %s", quoteResponse.AmountOut)
 	}
 
-	return amountOut / float64(tokenInAmount), route, nil
+	return OsmosisQuote{
+		Price:          sdk.NewDecFromInt(amountOut).QuoTruncate(sdk.NewDecFromInt(tokenInAmount)),
+		TokenInAmount:  tokenInAmount,
+		TokenOutAmount: amountOut,
+		Route:          route,
+		FeeRate:        splitFeeRate(route, pathFeeRates),
+	}, nil
 }
 
-// GetOsmosisBTCUSDTalance returns the balances in human readable exponents
-func GetOsmosisBTCUSDTBalance(SeedConfig) (float64, float64, error) {
+// pathFeeRate compounds the fee rates charged one after the other along a path
+func pathFeeRate(feeRates []sdk.Dec) sdk.Dec {
+	kept := sdk.OneDec()
+	for _, feeRate := range feeRates {
+		kept = kept.Mul(sdk.OneDec().Sub(feeRate))
+	}
+	return sdk.OneDec().Sub(kept)
+}
+
+// splitFeeRate averages the fee rate of each path of route, weighted by its amount in
+func splitFeeRate(route []poolmanagertypes.SwapAmountInSplitRoute, pathFeeRates []sdk.Dec) sdk.Dec {
+	total := routeTokenInAmount(route)
+	if !total.IsPositive() {
+		return sdk.ZeroDec()
+	}
+
+	weighted := sdk.ZeroDec()
+	for i, path := range route {
+		weighted = weighted.Add(pathFeeRates[i].MulInt(path.TokenInAmount))
+	}
+	return weighted.QuoInt(total)
+}
+
+// GetOsmosisPairBalance returns the base and quote balances in human readable exponents,
+// keyed by the pair's CEX asset names
+func GetOsmosisPairBalance(seedConfig SeedConfig, pair TradingPair) (Balances, error) {
 	grpcConnection := seedConfig.GRPCConnection
 	senderAddress := sdk.AccAddress(seedConfig.Key.PubKey().Address())
 
 	bankClient := banktypes.NewQueryClient(grpcConnection)
-	usdcBalanceResponse, err := bankClient.Balance(
+	baseBalanceResponse, err := bankClient.Balance(
 		context.Background(),
-		&banktypes.QueryBalanceRequest{Address: senderAddress.String(), Denom: USDCDenom},
+		&banktypes.QueryBalanceRequest{Address: senderAddress.String(), Denom: pair.BaseDenom},
 	)
 
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-	btcBalanceResponse, err := bankClient.Balance(
+	quoteBalanceResponse, err := bankClient.Balance(
 		context.Background(),
-		&banktypes.QueryBalanceRequest{Address: senderAddress.String(), Denom: BTCDenom},
+		&banktypes.QueryBalanceRequest{Address: senderAddress.String(), Denom: pair.QuoteDenom},
 	)
 
 	if err != nil {
-		return 0, 0, err
+		return nil, err
+	}
+	baseAmount := baseBalanceResponse.Balance.Amount
+	quoteAmount := quoteBalanceResponse.Balance.Amount
+	if seedConfig.DryRun {
+		baseAmount = baseAmount.Add(seedConfig.paperBalances.delta(pair.BaseDenom))
+		quoteAmount = quoteAmount.Add(seedConfig.paperBalances.delta(pair.QuoteDenom))
+	}
+
+	// balances on chain are never locked
+	balances := make(Balances)
+	balances.Set(osmosisVenue, pair.CEXBaseAsset, NewBalance(fromBaseUnits(baseAmount, pair.BaseExponent), sdk.ZeroDec()))
+	balances.Set(osmosisVenue, pair.CEXQuoteAsset, NewBalance(fromBaseUnits(quoteAmount, pair.QuoteExponent), sdk.ZeroDec()))
+	return balances, nil
+}
+
+// BuyOsmosisBase swaps the pair's quote asset into its base asset along the quoted route.
+// The swap reverts if it would return less base than the quote allows for after
+// slippage, or less than the CEX hedge needs to sell at cexSellPrice without a loss.
+// The swap bids auctionBid for top of block placement when it is set.
+// onSubmitted, when set, is called right before the swap is broadcasted.
+func BuyOsmosisBase(seedConfig SeedConfig, pair TradingPair, quote OsmosisQuote, cexSellPrice sdk.Dec, auctionBid sdk.Coin, onSubmitted TxSubmittedFunc) (SwapResult, error) {
+	if !cexSellPrice.IsPositive() {
+		return SwapResult{}, fmt.Errorf("invalid cex sell price %s", cexSellPrice)
+	}
+
+	// quote in, converted to base at the hedge price, in base units with exponent applied
+	tokenIn := fromBaseUnits(routeTokenInAmount(quote.Route), pair.QuoteExponent)
+	breakevenOut := toBaseUnitsRoundUp(tokenIn.QuoRoundUp(cexSellPrice), pair.BaseExponent)
+
+	tokenOutMinAmount, err := calculateTokenOutMinAmount(quote.TokenOutAmount, pair.MaxSlippageBps, breakevenOut)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	return seedConfig.osmosis().Swap(quote.Route, pair.QuoteDenom, tokenOutMinAmount, auctionBid, onSubmitted)
+}
+
+// SellOsmosisBase swaps the pair's base asset into its quote asset along the quoted route.
+// The swap reverts if it would return less quote than the quote allows for after
+// slippage, or less than the CEX hedge needs to buy back at cexBuyPrice without a loss.
+// The swap bids auctionBid for top of block placement when it is set.
+// onSubmitted, when set, is called right before the swap is broadcasted.
+func SellOsmosisBase(seedConfig SeedConfig, pair TradingPair, quote OsmosisQuote, cexBuyPrice sdk.Dec, auctionBid sdk.Coin, onSubmitted TxSubmittedFunc) (SwapResult, error) {
+	if !cexBuyPrice.IsPositive() {
+		return SwapResult{}, fmt.Errorf("invalid cex buy price %s", cexBuyPrice)
+	}
+
+	// base in, converted to quote at the hedge price, in quote units with exponent applied
+	tokenIn := fromBaseUnits(routeTokenInAmount(quote.Route), pair.BaseExponent)
+	breakevenOut := toBaseUnitsRoundUp(tokenIn.MulRoundUp(cexBuyPrice), pair.QuoteExponent)
+
+	tokenOutMinAmount, err := calculateTokenOutMinAmount(quote.TokenOutAmount, pair.MaxSlippageBps, breakevenOut)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	return seedConfig.osmosis().Swap(quote.Route, pair.BaseDenom, tokenOutMinAmount, auctionBid, onSubmitted)
+}
+
+// calculateTokenOutMinAmount returns the stricter of the quoted amount out less the
+// slippage tolerance, rounded up, and the breakeven amount out.
+// It errors when the quote itself no longer covers the breakeven amount.
+func calculateTokenOutMinAmount(quotedAmountOut sdk.Int, slippageBps uint64, breakeven sdk.Int) (sdk.Int, error) {
+	if slippageBps >= 10000 {
+		return sdk.Int{}, fmt.Errorf("invalid slippage tolerance of %d bps", slippageBps)
+	}
+
+	slippageMin := sdk.NewDecFromInt(quotedAmountOut).
+		MulInt64(int64(10000 - slippageBps)).
+		QuoInt64(10000).
+		Ceil().
+		TruncateInt()
+
+	if quotedAmountOut.LT(breakeven) {
+		return sdk.Int{}, fmt.Errorf("quoted amount out %s is below the breakeven amount %s", quotedAmountOut, breakeven)
+	}
+
+	return sdk.MaxInt(slippageMin, breakeven), nil
+}
+
+func routeTokenInAmount(route []poolmanagertypes.SwapAmountInSplitRoute) sdk.Int {
+	total := sdk.ZeroInt()
+	for _, r := range route {
+		total = total.Add(r.TokenInAmount)
+	}
+	return total
+}
+
+// SwapResult is the outcome of an Osmosis swap along with what was paid to get it included
+type SwapResult struct {
+	TxResult
+
+	TokenIn sdk.Coin
+	// TokenOut is the amount the swap returned, zero unless it was included
+	TokenOut sdk.Coin
+
+	// AuctionBid is the winning top of block bid, zero if the swap went through no auction
+	AuctionBid sdk.Coin
+	// Fees are the tx fees of the swap and of the bid carrying it
+	Fees sdk.Coins
+}
+
+func newSwapResult(result TxResult, route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string, fees sdk.Coins) SwapResult {
+	tokenOutDenom := ""
+	if len(route) > 0 && len(route[0].Pools) > 0 {
+		pools := route[0].Pools
+		tokenOutDenom = pools[len(pools)-1].TokenOutDenom
+	}
+
+	return swapResultFromTx(result, sdk.NewCoin(tokenInDenom, routeTokenInAmount(route)), tokenOutDenom, fees)
+}
+
+// swapResultFromTx builds the result of a swap of tokenIn from its tx, reading
+// the amount out from the tx's msg responses
+func swapResultFromTx(result TxResult, tokenIn sdk.Coin, tokenOutDenom string, fees sdk.Coins) SwapResult {
+	swapResult := SwapResult{
+		TxResult: result,
+		TokenIn:  tokenIn,
+		TokenOut: sdk.NewCoin(tokenOutDenom, sdk.ZeroInt()),
+		Fees:     fees,
+	}
+
+	if result.Status == TxStatusIncluded || result.Status == TxStatusSimulated {
+		tokenOutAmount, err := parseSplitRouteSwapTokenOut(result.Data)
+		if err != nil {
+			log.Println("Error parsing swap amount out of tx", result.TxHash, ":", err)
+		} else {
+			swapResult.TokenOut.Amount = tokenOutAmount
+		}
+	}
+
+	return swapResult
+}
+
+// parseSplitRouteSwapTokenOut reads the amount out of the split route swap
+// response in the hex encoded msg data of a tx
+func parseSplitRouteSwapTokenOut(data string) (sdk.Int, error) {
+	bz, err := hex.DecodeString(data)
+	if err != nil {
+		return sdk.Int{}, err
 	}
-	usdcAmount := usdcBalanceResponse.Balance.Amount
-	btcAmount := btcBalanceResponse.Balance.Amount
 
-	usdcAmountWithExponent := float64(usdcAmount.Int64()) / math.Pow(10, osmosisUSDCExponent)
-	btcAmountWithExponent := float64(btcAmount.Int64()) / math.Pow(10, osmosisWBTCExponent)
-	return usdcAmountWithExponent, btcAmountWithExponent, nil
+	var msgData sdk.TxMsgData
+	if err := msgData.Unmarshal(bz); err != nil {
+		return sdk.Int{}, err
+	}
+
+	for _, msgResponse := range msgData.MsgResponses {
+		var swapResponse poolmanagertypes.MsgSplitRouteSwapExactAmountInResponse
+		if msgResponse.TypeUrl != "/"+proto.MessageName(&swapResponse) {
+			continue
+		}
+
+		if err := swapResponse.Unmarshal(msgResponse.Value); err != nil {
+			return sdk.Int{}, err
+		}
+		return swapResponse.TokenOutAmount, nil
+	}
+
+	return sdk.Int{}, fmt.Errorf("no split route swap response in tx data")
 }
 
-func BuyOsmosisBTC(seedConfig SeedConfig, route []poolmanagertypes.SwapAmountInSplitRoute, binanceBTCPrice float64) error {
-	return SwapWithTopOfBlockAuction(seedConfig, route, USDCDenom, 1)
+// EstimateSwapFee simulates a swap along route and returns the tx fee it would pay
+func EstimateSwapFee(seedConfig SeedConfig, route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string) (sdk.Coins, error) {
+	grpcConnection := seedConfig.GRPCConnection
+	txClient := txtypes.NewServiceClient(grpcConnection)
+	ac := auth.NewQueryClient(grpcConnection)
+	txFeesClient := txfeestypes.NewQueryClient(grpcConnection)
+
+	// any amount out is accepted, the fee does not depend on it
+	swapTokenMsg := &poolmanagertypes.MsgSplitRouteSwapExactAmountIn{
+		Sender:            OsmosisAddress(seedConfig),
+		Routes:            route,
+		TokenInDenom:      tokenInDenom,
+		TokenOutMinAmount: sdk.OneInt(),
+	}
+
+	_, fee, err := estimateGasAndFee(seedConfig, ac, txClient, txFeesClient, []sdk.Msg{swapTokenMsg})
+	return fee, err
 }
 
-func SellOsmosisBTC(seedConfig SeedConfig, route []poolmanagertypes.SwapAmountInSplitRoute, binanceBTCPrice float64) error {
-	return SwapWithTopOfBlockAuction(seedConfig, route, BTCDenom, 1)
+// Swap broadcasts a split route swap as a regular tx, outside of the auction.
+// onSubmitted, when set, is called with the swap's hash right before it is broadcasted.
+func Swap(seedConfig SeedConfig,
+	route []poolmanagertypes.SwapAmountInSplitRoute,
+	tokenInDenom string,
+	tokenOutMinAmount sdk.Int,
+	onSubmitted TxSubmittedFunc,
+) (SwapResult, error) {
+	grpcConnection := seedConfig.GRPCConnection
+	senderAddress := sdk.AccAddress(seedConfig.Key.PubKey().Address())
+	txClient := txtypes.NewServiceClient(grpcConnection)
+	ac := auth.NewQueryClient(grpcConnection)
+	tm := tmservice.NewServiceClient(grpcConnection)
+	txFeesClient := txfeestypes.NewQueryClient(grpcConnection)
+
+	swapTokenMsg := &poolmanagertypes.MsgSplitRouteSwapExactAmountIn{
+		Sender:            senderAddress.String(),
+		Routes:            route,
+		TokenInDenom:      tokenInDenom,
+		TokenOutMinAmount: tokenOutMinAmount,
+	}
+
+	swapGas, swapFee, err := estimateGasAndFee(seedConfig, ac, txClient, txFeesClient, []sdk.Msg{swapTokenMsg})
+	if err != nil {
+		return SwapResult{}, fmt.Errorf("error estimating swap gas: %v", err)
+	}
+
+	if seedConfig.DryRun {
+		simulated, err := simulateSignedSwap(seedConfig, tm, ac, txClient, []sdk.Msg{swapTokenMsg}, swapGas, swapFee)
+		if err != nil {
+			return SwapResult{}, err
+		}
+		result := newSwapResult(simulated, route, tokenInDenom, swapFee)
+		seedConfig.paperBalances.apply(result)
+		return result, nil
+	}
+
+	result, err := SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		nil,
+		seedConfig.EncodingConfig,
+		tm,
+		ac,
+		txClient,
+		seedConfig.ChainID,
+		[]sdk.Msg{swapTokenMsg},
+		[]uint64{},
+		swapGas,
+		swapFee,
+		onSubmitted,
+	)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	return newSwapResult(result, route, tokenInDenom, result.Fee), nil
 }
 
+// SwapWithTopOfBlockAuction broadcasts a bid for top of block carrying the swap.
+// onSubmitted, when set, is called with the swap's hash and the bid's timeout
+// height right before the bid is broadcasted, the swap can't land after the bid.
 func SwapWithTopOfBlockAuction(seedConfig SeedConfig,
 	route []poolmanagertypes.SwapAmountInSplitRoute,
 	tokenInDenom string,
-	tokenOutMinAmount uint64,
-) error {
+	tokenOutMinAmount sdk.Int,
+	bid sdk.Coin,
+	onSubmitted TxSubmittedFunc,
+) (SwapResult, error) {
 	grpcConnection := seedConfig.GRPCConnection
 	senderAddress := sdk.AccAddress(seedConfig.Key.PubKey().Address())
 	txClient := txtypes.NewServiceClient(grpcConnection)
 	ac := auth.NewQueryClient(grpcConnection)
 	tm := tmservice.NewServiceClient(grpcConnection)
+	txFeesClient := txfeestypes.NewQueryClient(grpcConnection)
 
 	swapTokenMsg := &poolmanagertypes.MsgSplitRouteSwapExactAmountIn{
 		Sender:            senderAddress.String(),
 		Routes:            route,
 		TokenInDenom:      tokenInDenom,
-		TokenOutMinAmount: sdk.NewIntFromUint64(tokenOutMinAmount),
+		TokenOutMinAmount: tokenOutMinAmount,
+	}
+
+	// simulate before signing anything, a swap that would fail aborts the arb here
+	swapGas, swapFee, err := estimateGasAndFee(seedConfig, ac, txClient, txFeesClient, []sdk.Msg{swapTokenMsg})
+	if err != nil {
+		return SwapResult{}, fmt.Errorf("error estimating swap gas: %v", err)
 	}
 
 	txBytes1, err := SignAuthenticatorMsgMultiSignersBytes(
@@ -172,21 +480,40 @@ func SwapWithTopOfBlockAuction(seedConfig SeedConfig,
 		[]sdk.Msg{swapTokenMsg},
 		[]uint64{},
 		1,
+		swapGas,
+		swapFee,
 	)
 
 	if err != nil {
-		return err
+		return SwapResult{}, err
 	}
 
 	bundle := [][]byte{txBytes1}
 
 	bidMsg := &auctiontypes.MsgAuctionBid{
 		Bidder:       senderAddress.String(),
-		Bid:          sdk.NewCoin(BidDenom, sdk.NewInt(100)),
+		Bid:          bid,
 		Transactions: bundle,
 	}
 
-	err = SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
+	bidGas, bidFee, err := estimateGasAndFee(seedConfig, ac, txClient, txFeesClient, []sdk.Msg{bidMsg})
+	if err != nil {
+		return SwapResult{}, fmt.Errorf("error estimating auction bid gas: %v", err)
+	}
+
+	if seedConfig.DryRun {
+		return simulateSwapWithTopOfBlockAuction(seedConfig, tm, ac, txClient, route, tokenInDenom, swapTokenMsg, txBytes1, swapFee, bidMsg, bidGas, bidFee)
+	}
+
+	swapHash := TxHash(txBytes1)
+	var onBidSubmitted TxSubmittedFunc
+	if onSubmitted != nil {
+		onBidSubmitted = func(_ string, timeoutHeight uint64) error {
+			return onSubmitted(swapHash, timeoutHeight)
+		}
+	}
+
+	bidResult, err := SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 		[]cryptotypes.PrivKey{seedConfig.Key},
 		[]cryptotypes.PrivKey{seedConfig.Key},
 		nil,
@@ -197,10 +524,132 @@ func SwapWithTopOfBlockAuction(seedConfig SeedConfig,
 		seedConfig.ChainID,
 		[]sdk.Msg{bidMsg},
 		[]uint64{},
+		bidGas,
+		bidFee,
+		onBidSubmitted,
+	)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	var result SwapResult
+	switch bidResult.Status {
+	case TxStatusIncluded:
+		// a winning bid lands in the same block as its bundle
+		swapResult, err := WaitForTx(context.Background(), txClient, tm, swapHash, uint64(bidResult.Height))
+		if err != nil {
+			return SwapResult{}, err
+		}
+		result = newSwapResult(swapResult, route, tokenInDenom, bidResult.Fee.Add(swapResult.Fee...))
+		result.AuctionBid = bid
+	case TxStatusExpired:
+		result = newSwapResult(TxResult{TxHash: swapHash, Status: TxStatusAuctionLost}, route, tokenInDenom, nil)
+	default:
+		// the bid itself failed, its bundle was never executed
+		result = newSwapResult(bidResult, route, tokenInDenom, bidResult.Fee)
+	}
+
+	return result, nil
+}
+
+// simulateSwapWithTopOfBlockAuction signs and simulates the bid carrying the signed
+// swap, then simulates the swap on its own for its amount out, since the bundled
+// swap is signed for the sequence after the bid's
+func simulateSwapWithTopOfBlockAuction(
+	seedConfig SeedConfig,
+	tm tmservice.ServiceClient,
+	ac auth.QueryClient,
+	txClient txtypes.ServiceClient,
+	route []poolmanagertypes.SwapAmountInSplitRoute,
+	tokenInDenom string,
+	swapTokenMsg *poolmanagertypes.MsgSplitRouteSwapExactAmountIn,
+	swapTxBytes []byte,
+	swapFee sdk.Coins,
+	bidMsg *auctiontypes.MsgAuctionBid,
+	bidGas uint64,
+	bidFee sdk.Coins,
+) (SwapResult, error) {
+	if _, err := simulateSignedSwap(seedConfig, tm, ac, txClient, []sdk.Msg{bidMsg}, bidGas, bidFee); err != nil {
+		return SwapResult{}, fmt.Errorf("error simulating auction bid: %v", err)
+	}
+
+	simulation, err := SimulateAuthenticatorMsg(
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		seedConfig.EncodingConfig,
+		ac,
+		txClient,
+		[]sdk.Msg{swapTokenMsg},
+		[]uint64{},
+	)
+	if err != nil {
+		return SwapResult{}, err
+	}
+	swapResult, err := simulatedTxResult(TxHash(swapTxBytes), simulation, swapFee)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	result := newSwapResult(swapResult, route, tokenInDenom, bidFee.Add(swapFee...))
+	result.AuctionBid = bidMsg.Bid
+	seedConfig.paperBalances.apply(result)
+	return result, nil
+}
+
+// estimateGasAndFee simulates msgs and returns the adjusted gas limit and the fee
+// for it at the current base fee
+func estimateGasAndFee(
+	seedConfig SeedConfig,
+	ac auth.QueryClient,
+	txClient txtypes.ServiceClient,
+	txFeesClient txfeestypes.QueryClient,
+	msgs []sdk.Msg,
+) (uint64, sdk.Coins, error) {
+	simulation, err := SimulateAuthenticatorMsg(
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		seedConfig.EncodingConfig,
+		ac,
+		txClient,
+		msgs,
+		[]uint64{},
 	)
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
 
-	return nil
+	gas := uint64(math.Ceil(float64(simulation.GasInfo.GasUsed) * seedConfig.GasAdjustment))
+	fee, err := GetFeeForGas(txFeesClient, gas)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return gas, fee, nil
+}
+
+// WatchOsmosisBlocks polls the node for its latest block and calls onBlock once
+// for every new height, until ctx is cancelled
+func WatchOsmosisBlocks(ctx context.Context, seedConfig SeedConfig, pollInterval time.Duration, onBlock func(height int64)) {
+	tm := tmservice.NewServiceClient(seedConfig.GRPCConnection)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastHeight int64
+	for {
+		block, err := tm.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Error fetching latest Osmosis block:", err)
+		} else if height := block.Block.Header.Height; height > lastHeight {
+			lastHeight = height
+			onBlock(height)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
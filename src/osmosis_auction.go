@@ -0,0 +1,100 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
+)
+
+// GetAuctionParams returns the current x/auction params. The reserve fee's denom
+// is the denom bids have to be made in.
+func GetAuctionParams(seedConfig SeedConfig) (auctiontypes.Params, error) {
+	auctionClient := auctiontypes.NewQueryClient(seedConfig.GRPCConnection)
+	res, err := auctionClient.Params(context.Background(), &auctiontypes.QueryParamsRequest{})
+	if err != nil {
+		return auctiontypes.Params{}, fmt.Errorf("error fetching auction params: %v", err)
+	}
+	return res.Params, nil
+}
+
+// CalculateAuctionBid sizes the top of block bid for an arb expected to make
+// expectedProfit, in human readable units of the pair's quote asset.
+// The bid is the pair's bid fraction of the profit, at least the reserve fee
+// and at most the pair's max bid, rounded down to the reserve fee plus a
+// multiple of the auction's min bid increment. ok is false when the profit can't
+// cover the reserve fee, in which case the swap should not go through the auction.
+// The params are queried on every call, so an arb should size its bid once.
+func CalculateAuctionBid(seedConfig SeedConfig, pair TradingPair, expectedProfit sdk.Dec) (bid sdk.Coin, ok bool, err error) {
+	params, err := seedConfig.osmosis().AuctionParams()
+	if err != nil {
+		return sdk.Coin{}, false, err
+	}
+	reserveFee := params.ReserveFee
+
+	if !expectedProfit.IsPositive() {
+		return sdk.Coin{}, false, nil
+	}
+
+	profit, err := convertQuoteToDenom(seedConfig, pair, expectedProfit, reserveFee.Denom)
+	if err != nil {
+		return sdk.Coin{}, false, fmt.Errorf("error converting expected profit to %s: %v", reserveFee.Denom, err)
+	}
+
+	if profit.LT(reserveFee.Amount) {
+		log.Println("Expected profit of", profit, reserveFee.Denom, "does not cover the auction reserve fee")
+		return sdk.Coin{}, false, nil
+	}
+
+	bidAmount := sdk.NewDecFromInt(profit).Mul(floatToDec(pair.AuctionBidFraction)).TruncateInt()
+	bidAmount = sdk.MaxInt(bidAmount, reserveFee.Amount)
+
+	maxBid := sdk.NewInt(pair.MaxAuctionBid)
+	if maxBid.LT(reserveFee.Amount) {
+		log.Println("Max auction bid of", maxBid, "is below the auction reserve fee")
+		return sdk.Coin{}, false, nil
+	}
+	bidAmount = sdk.MinInt(bidAmount, maxBid)
+
+	// bids outbid each other by whole increments above the reserve fee
+	if increment := params.MinBidIncrement.Amount; !increment.IsNil() && increment.IsPositive() {
+		bidAmount = reserveFee.Amount.Add(bidAmount.Sub(reserveFee.Amount).Quo(increment).Mul(increment))
+	}
+
+	return sdk.NewCoin(reserveFee.Denom, bidAmount), true, nil
+}
+
+// convertQuoteToDenom converts amount of the pair's quote asset, in human readable
+// units, to the smallest unit of denom at the current Osmosis price, rounding down
+func convertQuoteToDenom(seedConfig SeedConfig, pair TradingPair, amount sdk.Dec, denom string) (sdk.Int, error) {
+	amountWithExponentApplied := toBaseUnits(amount, pair.QuoteExponent)
+	if denom == pair.QuoteDenom {
+		return amountWithExponentApplied, nil
+	}
+	if amountWithExponentApplied.IsZero() {
+		return sdk.ZeroInt(), nil
+	}
+
+	quote, err := getOsmosisPriceAndRoute(seedConfig, pair.QuoteDenom, denom, amountWithExponentApplied)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	return quote.TokenOutAmount, nil
+}
+
+// convertDenomToQuote values coin in human readable units of the pair's quote
+// asset at the current Osmosis price
+func convertDenomToQuote(seedConfig SeedConfig, pair TradingPair, coin sdk.Coin) (sdk.Dec, error) {
+	quoteAmount := coin.Amount
+	if coin.Denom != pair.QuoteDenom && coin.Amount.IsPositive() {
+		quote, err := getOsmosisPriceAndRoute(seedConfig, coin.Denom, pair.QuoteDenom, coin.Amount)
+		if err != nil {
+			return sdk.Dec{}, err
+		}
+		quoteAmount = quote.TokenOutAmount
+	}
+
+	return fromBaseUnits(quoteAmount, pair.QuoteExponent), nil
+}
@@ -0,0 +1,56 @@
+package src
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
+)
+
+func TestCalculateAuctionBid(t *testing.T) {
+	tests := []struct {
+		name string
+		// profit is the expected profit in USDC, the bids are in its smallest unit
+		profit    string
+		maxBid    int64
+		increment int64
+		wantBid   int64
+		wantOK    bool
+	}{
+		{name: "no profit", profit: "0", maxBid: 10_000_000, increment: 1_000_000},
+		{name: "profit below the reserve fee", profit: "0.999999", maxBid: 10_000_000, increment: 1_000_000},
+		{name: "bid raised to the reserve fee", profit: "1.5", maxBid: 10_000_000, increment: 1_000_000, wantBid: 1_000_000, wantOK: true},
+		{name: "bid rounded down to an increment", profit: "7", maxBid: 10_000_000, increment: 1_000_000, wantBid: 3_000_000, wantOK: true},
+		{name: "bid without an increment", profit: "7", maxBid: 10_000_000, wantBid: 3_500_000, wantOK: true},
+		{name: "bid at the cap", profit: "100", maxBid: 10_000_000, increment: 1_000_000, wantBid: 10_000_000, wantOK: true},
+		{name: "cap off an increment", profit: "100", maxBid: 10_500_000, increment: 1_000_000, wantBid: 10_000_000, wantOK: true},
+		{name: "cap below the reserve fee", profit: "100", maxBid: 999_999, increment: 1_000_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair := testArbPair()
+			pair.AuctionBidFraction = 0.5
+			pair.MaxAuctionBid = tt.maxBid
+
+			osmosis := NewFakeOsmosis()
+			osmosis.SetAuctionParams(auctiontypes.Params{
+				ReserveFee:      sdk.NewInt64Coin(pair.QuoteDenom, 1_000_000),
+				MinBidIncrement: sdk.NewInt64Coin(pair.QuoteDenom, tt.increment),
+			})
+
+			bid, ok, err := CalculateAuctionBid(SeedConfig{Osmosis: osmosis}, pair, sdk.MustNewDecFromStr(tt.profit))
+			if err != nil {
+				t.Fatalf("CalculateAuctionBid() = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("CalculateAuctionBid() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if want := sdk.NewInt64Coin(pair.QuoteDenom, tt.wantBid); !bid.IsEqual(want) {
+				t.Errorf("CalculateAuctionBid() = %s, want %s", bid, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package src
+
+import (
+	"log"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
+
+	poolmanagertypes "github.com/osmosis-labs/osmosis/v25/x/poolmanager/types"
+)
+
+// OsmosisClient is everything an arb reads from and sends to Osmosis. Amounts are
+// in the smallest unit of their denom. Unless SeedConfig.Osmosis is set, the
+// node at SeedConfig.GRPCConnection and the router of SeedConfig.Router are used.
+type OsmosisClient interface {
+	// PairBalance returns the account's free base and quote, see GetOsmosisPairBalance
+	PairBalance(pair TradingPair) (Balances, error)
+	// Quote prices a swap of tokenInAmount of tokenInDenom into tokenOutDenom
+	Quote(tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error)
+	// EstimateQuote re-prices the routes of quote on chain and returns their total amount out
+	EstimateQuote(tokenInDenom string, quote OsmosisQuote) (sdk.Int, error)
+	// EstimateSwapFee returns the tx fee a swap along route would pay
+	EstimateSwapFee(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string) (sdk.Coins, error)
+	// AuctionParams returns the top of block auction's params
+	AuctionParams() (auctiontypes.Params, error)
+	// Swap sends a swap along route, bidding bid for top of block placement
+	// unless it is unset. onSubmitted, when set, is called right before the
+	// swap is broadcasted.
+	Swap(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string, tokenOutMinAmount sdk.Int, bid sdk.Coin, onSubmitted TxSubmittedFunc) (SwapResult, error)
+}
+
+// osmosis returns the OsmosisClient arbs go through
+func (s SeedConfig) osmosis() OsmosisClient {
+	if s.Osmosis != nil {
+		return s.Osmosis
+	}
+	return nodeOsmosis{seedConfig: s}
+}
+
+// nodeOsmosis is the OsmosisClient of a SeedConfig's node, key and router
+type nodeOsmosis struct {
+	seedConfig SeedConfig
+}
+
+var _ OsmosisClient = nodeOsmosis{}
+
+func (n nodeOsmosis) PairBalance(pair TradingPair) (Balances, error) {
+	return GetOsmosisPairBalance(n.seedConfig, pair)
+}
+
+func (n nodeOsmosis) Quote(tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error) {
+	if n.seedConfig.Router == OsmosisRouterLocal {
+		return getLocalPriceAndRoute(n.seedConfig, tokenInDenom, tokenOutDenom, tokenInAmount)
+	}
+	return getSQSPriceAndRoute(tokenInDenom, tokenOutDenom, tokenInAmount)
+}
+
+func (n nodeOsmosis) EstimateQuote(tokenInDenom string, quote OsmosisQuote) (sdk.Int, error) {
+	return EstimateOsmosisQuote(n.seedConfig, tokenInDenom, quote)
+}
+
+func (n nodeOsmosis) EstimateSwapFee(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string) (sdk.Coins, error) {
+	return EstimateSwapFee(n.seedConfig, route, tokenInDenom)
+}
+
+func (n nodeOsmosis) AuctionParams() (auctiontypes.Params, error) {
+	return GetAuctionParams(n.seedConfig)
+}
+
+func (n nodeOsmosis) Swap(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string, tokenOutMinAmount sdk.Int, bid sdk.Coin, onSubmitted TxSubmittedFunc) (SwapResult, error) {
+	if !bid.IsValid() || bid.IsZero() {
+		log.Println("Skipping the top of block auction, sending the swap on its own")
+		return Swap(n.seedConfig, route, tokenInDenom, tokenOutMinAmount, onSubmitted)
+	}
+
+	log.Println("Bidding", bid, "for top of block")
+	return SwapWithTopOfBlockAuction(n.seedConfig, route, tokenInDenom, tokenOutMinAmount, bid, onSubmitted)
+}
@@ -0,0 +1,74 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	poolmanagerqueryproto "github.com/osmosis-labs/osmosis/v25/x/poolmanager/client/queryproto"
+)
+
+// EstimateOsmosisQuote re-prices every split route of quote through the
+// poolmanager's estimate queries on our node and returns their total amount out
+func EstimateOsmosisQuote(seedConfig SeedConfig, tokenInDenom string, quote OsmosisQuote) (sdk.Int, error) {
+	defer observeLatency("node_estimate_swap", time.Now())
+
+	poolClient := poolmanagerqueryproto.NewQueryClient(seedConfig.GRPCConnection)
+
+	total := sdk.ZeroInt()
+	for _, route := range quote.Route {
+		if len(route.Pools) == 0 {
+			return sdk.Int{}, fmt.Errorf("quoted route has no pools")
+		}
+		tokenIn := sdk.NewCoin(tokenInDenom, route.TokenInAmount).String()
+
+		var (
+			res *poolmanagerqueryproto.EstimateSwapExactAmountInResponse
+			err error
+		)
+		if len(route.Pools) == 1 {
+			res, err = poolClient.EstimateSinglePoolSwapExactAmountIn(context.Background(), &poolmanagerqueryproto.EstimateSinglePoolSwapExactAmountInRequest{
+				PoolId:        route.Pools[0].PoolId,
+				TokenIn:       tokenIn,
+				TokenOutDenom: route.Pools[0].TokenOutDenom,
+			})
+		} else {
+			res, err = poolClient.EstimateSwapExactAmountIn(context.Background(), &poolmanagerqueryproto.EstimateSwapExactAmountInRequest{
+				TokenIn: tokenIn,
+				Routes:  route.Pools,
+			})
+		}
+		if err != nil {
+			return sdk.Int{}, fmt.Errorf("error estimating swap of %s through pools %v: %v", tokenIn, route.Pools, err)
+		}
+
+		total = total.Add(res.TokenOutAmount)
+	}
+
+	return total, nil
+}
+
+// VerifyOsmosisQuote returns an error when the quoted amount out is further from
+// the node's estimate of the same routes than the pair's tolerance
+func VerifyOsmosisQuote(seedConfig SeedConfig, pair TradingPair, tokenInDenom string, quote OsmosisQuote) error {
+	estimated, err := seedConfig.osmosis().EstimateQuote(tokenInDenom, quote)
+	if err != nil {
+		return err
+	}
+
+	quoted := quote.TokenOutAmount
+	if !quoted.IsPositive() {
+		return fmt.Errorf("quoted amount out %s is not positive", quoted)
+	}
+
+	deviationBps := sdk.NewDecFromInt(quoted.Sub(estimated).Abs()).MulInt64(10000).QuoInt(quoted)
+	if deviationBps.GT(sdk.NewDec(int64(pair.MaxQuoteDeviationBps))) {
+		log.Println("Rejecting", pair.Name, "quote: quoted amount out", quoted, "deviates", deviationBps, "bps from the on-chain estimate", estimated)
+		return fmt.Errorf("quoted amount out %s deviates %s bps from the on-chain estimate %s", quoted, deviationBps, estimated)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package src
+
+import (
+	"fmt"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auctiontypes "github.com/skip-mev/block-sdk/x/auction/types"
+
+	poolmanagertypes "github.com/osmosis-labs/osmosis/v25/x/poolmanager/types"
+)
+
+// FakeOsmosis is an in-memory OsmosisClient. Every pair of denoms with a price
+// set trades through a single pool at that price less its fee rate, swaps move
+// the account balances and are included right away, and every swap pays the
+// same tx fee.
+type FakeOsmosis struct {
+	mu sync.Mutex
+
+	pools         map[[2]string]sdk.Dec
+	feeRate       sdk.Dec
+	balances      map[string]sdk.Int
+	swapFee       sdk.Coins
+	auctionParams auctiontypes.Params
+	swaps         []SwapResult
+}
+
+var _ OsmosisClient = (*FakeOsmosis)(nil)
+
+// fakePoolID is the id of every FakeOsmosis pool
+const fakePoolID = 1
+
+func NewFakeOsmosis() *FakeOsmosis {
+	return &FakeOsmosis{
+		pools:    make(map[[2]string]sdk.Dec),
+		feeRate:  sdk.ZeroDec(),
+		balances: make(map[string]sdk.Int),
+	}
+}
+
+// SetPrice sets the price of baseDenom in smallest units of quoteDenom per
+// smallest unit of baseDenom, swaps the other way trade at its inverse
+func (f *FakeOsmosis) SetPrice(baseDenom, quoteDenom string, price sdk.Dec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pools[[2]string{baseDenom, quoteDenom}] = price
+	f.pools[[2]string{quoteDenom, baseDenom}] = sdk.OneDec().Quo(price)
+}
+
+// SetFeeRate sets the share of the amount in every pool charges, zero by default
+func (f *FakeOsmosis) SetFeeRate(feeRate sdk.Dec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.feeRate = feeRate
+}
+
+func (f *FakeOsmosis) SetBalance(denom string, amount sdk.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.balances[denom] = amount
+}
+
+// Balance returns the balance of denom, zero if it was never set
+func (f *FakeOsmosis) Balance(denom string) sdk.Int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.balance(denom)
+}
+
+// SetSwapFee sets the tx fee every swap pays, none by default
+func (f *FakeOsmosis) SetSwapFee(fee sdk.Coins) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.swapFee = fee
+}
+
+func (f *FakeOsmosis) SetAuctionParams(params auctiontypes.Params) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auctionParams = params
+}
+
+// Swaps returns the swaps sent so far, in order
+func (f *FakeOsmosis) Swaps() []SwapResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]SwapResult{}, f.swaps...)
+}
+
+func (f *FakeOsmosis) PairBalance(pair TradingPair) (Balances, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	balances := make(Balances)
+	balances.Set(osmosisVenue, pair.CEXBaseAsset, NewBalance(fromBaseUnits(f.balance(pair.BaseDenom), pair.BaseExponent), sdk.ZeroDec()))
+	balances.Set(osmosisVenue, pair.CEXQuoteAsset, NewBalance(fromBaseUnits(f.balance(pair.QuoteDenom), pair.QuoteExponent), sdk.ZeroDec()))
+	return balances, nil
+}
+
+func (f *FakeOsmosis) Quote(tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	amountOut, err := f.amountOut(tokenInDenom, tokenOutDenom, tokenInAmount)
+	if err != nil {
+		return OsmosisQuote{}, err
+	}
+	route := []poolmanagertypes.SwapAmountInSplitRoute{{
+		Pools:         []poolmanagertypes.SwapAmountInRoute{{PoolId: fakePoolID, TokenOutDenom: tokenOutDenom}},
+		TokenInAmount: tokenInAmount,
+	}}
+	return OsmosisQuote{
+		Price:          sdk.NewDecFromInt(amountOut).QuoTruncate(sdk.NewDecFromInt(tokenInAmount)),
+		TokenInAmount:  tokenInAmount,
+		TokenOutAmount: amountOut,
+		Route:          route,
+		FeeRate:        f.feeRate,
+	}, nil
+}
+
+func (f *FakeOsmosis) EstimateQuote(tokenInDenom string, quote OsmosisQuote) (sdk.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.routeAmountOut(quote.Route, tokenInDenom)
+}
+
+func (f *FakeOsmosis) EstimateSwapFee(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string) (sdk.Coins, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.swapFee, nil
+}
+
+func (f *FakeOsmosis) AuctionParams() (auctiontypes.Params, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.auctionParams, nil
+}
+
+func (f *FakeOsmosis) Swap(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string, tokenOutMinAmount sdk.Int, bid sdk.Coin, onSubmitted TxSubmittedFunc) (SwapResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tokenIn := sdk.NewCoin(tokenInDenom, routeTokenInAmount(route))
+	if f.balance(tokenInDenom).LT(tokenIn.Amount) {
+		return SwapResult{}, fmt.Errorf("insufficient %s balance", tokenInDenom)
+	}
+	amountOut, err := f.routeAmountOut(route, tokenInDenom)
+	if err != nil {
+		return SwapResult{}, err
+	}
+
+	hash := fmt.Sprintf("FAKE%d", len(f.swaps)+1)
+	if onSubmitted != nil {
+		if err := onSubmitted(hash, 0); err != nil {
+			return SwapResult{}, err
+		}
+	}
+
+	fees := f.swapFee
+	if bid.IsValid() && !bid.IsZero() {
+		fees = fees.Add(bid)
+	} else {
+		bid = sdk.Coin{}
+	}
+	for _, fee := range fees {
+		f.balances[fee.Denom] = f.balance(fee.Denom).Sub(fee.Amount)
+	}
+
+	tokenOutDenom := route[0].Pools[len(route[0].Pools)-1].TokenOutDenom
+	result := SwapResult{
+		TxResult:   TxResult{TxHash: hash, Status: TxStatusIncluded, Fee: f.swapFee},
+		TokenIn:    tokenIn,
+		TokenOut:   sdk.NewCoin(tokenOutDenom, sdk.ZeroInt()),
+		AuctionBid: bid,
+		Fees:       f.swapFee,
+	}
+	if amountOut.LT(tokenOutMinAmount) {
+		result.Status, result.Code = TxStatusFailed, 1
+		result.Log = fmt.Sprintf("token amount calculated (%s) is lesser than min amount (%s)", amountOut, tokenOutMinAmount)
+	} else {
+		f.balances[tokenInDenom] = f.balance(tokenInDenom).Sub(tokenIn.Amount)
+		f.balances[tokenOutDenom] = f.balance(tokenOutDenom).Add(amountOut)
+		result.TokenOut.Amount = amountOut
+	}
+	f.swaps = append(f.swaps, result)
+
+	return result, nil
+}
+
+// routeAmountOut returns what swapping along every path of route returns. Must be called with mu held.
+func (f *FakeOsmosis) routeAmountOut(route []poolmanagertypes.SwapAmountInSplitRoute, tokenInDenom string) (sdk.Int, error) {
+	if len(route) == 0 {
+		return sdk.Int{}, fmt.Errorf("route has no paths")
+	}
+
+	total := sdk.ZeroInt()
+	for _, path := range route {
+		if len(path.Pools) == 0 {
+			return sdk.Int{}, fmt.Errorf("route has no pools")
+		}
+		denom, amount := tokenInDenom, path.TokenInAmount
+		for _, pool := range path.Pools {
+			out, err := f.amountOut(denom, pool.TokenOutDenom, amount)
+			if err != nil {
+				return sdk.Int{}, err
+			}
+			denom, amount = pool.TokenOutDenom, out
+		}
+		total = total.Add(amount)
+	}
+	return total, nil
+}
+
+// amountOut returns what swapping amount of tokenInDenom into tokenOutDenom returns. Must be called with mu held.
+func (f *FakeOsmosis) amountOut(tokenInDenom, tokenOutDenom string, amount sdk.Int) (sdk.Int, error) {
+	price, ok := f.pools[[2]string{tokenInDenom, tokenOutDenom}]
+	if !ok {
+		return sdk.Int{}, fmt.Errorf("no pool between %s and %s", tokenInDenom, tokenOutDenom)
+	}
+	return sdk.NewDecFromInt(amount).Mul(sdk.OneDec().Sub(f.feeRate)).Mul(price).TruncateInt(), nil
+}
+
+// balance returns the balance of denom, zero if it was never set. Must be called with mu held.
+func (f *FakeOsmosis) balance(denom string) sdk.Int {
+	if balance, ok := f.balances[denom]; ok {
+		return balance
+	}
+	return sdk.ZeroInt()
+}
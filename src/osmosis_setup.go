@@ -3,7 +3,9 @@ package src
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
@@ -18,12 +20,39 @@ type SeedConfig struct {
 	GRPCConnection *grpc.ClientConn
 	EncodingConfig params.EncodingConfig
 	Key            *secp256k1.PrivKey
-	DenomMap       map[string]string
+
+	// Denoms resolves the display exponent and symbol of denoms.
+	// DenomMap holds the symbol of every denom traded, keyed by denom.
+	Denoms   *DenomRegistry
+	DenomMap map[string]string
+
+	// GasAdjustment multiplies the simulated gas of a tx to get its gas limit
+	GasAdjustment float64
+
+	// Router selects where swaps are quoted and routed, OsmosisRouterSQS or OsmosisRouterLocal
+	Router string
+	// RouteIntermediateDenoms are the denoms the local router's two hop routes may
+	// go through, see ArbConfig.RouteIntermediateDenoms
+	RouteIntermediateDenoms []string
+
+	// DryRun simulates swaps instead of broadcasting them, see EnableDryRun
+	DryRun        bool
+	paperBalances *paperBalances
+
+	// Osmosis, when set, replaces the node and router arbs quote, estimate and swap with
+	Osmosis OsmosisClient
 }
 
 const (
 	CHAIN_ID  = "osmosis-1"
 	FEE_DENOM = "uosmo"
+
+	DEFAULT_GAS_ADJUSTMENT = 1.3
+
+	// OsmosisRouterSQS quotes swaps through the public sidecar query server
+	OsmosisRouterSQS = "sqs"
+	// OsmosisRouterLocal routes swaps locally from pool state queried from our node
+	OsmosisRouterLocal = "local"
 )
 
 var (
@@ -46,11 +75,37 @@ func OsmosisInit() (SeedConfig, error) {
 	}
 	privKey := &secp256k1.PrivKey{Key: bz}
 
+	gasAdjustment := DEFAULT_GAS_ADJUSTMENT
+	if gasAdjustmentStr := os.Getenv("GAS_ADJUSTMENT"); gasAdjustmentStr != "" {
+		gasAdjustment, err = strconv.ParseFloat(gasAdjustmentStr, 64)
+		if err != nil {
+			return SeedConfig{}, fmt.Errorf("invalid GAS_ADJUSTMENT: %v", err)
+		}
+	}
+
+	router := os.Getenv("OSMOSIS_ROUTER")
+	switch router {
+	case "":
+		router = OsmosisRouterSQS
+	case OsmosisRouterSQS, OsmosisRouterLocal:
+	default:
+		return SeedConfig{}, fmt.Errorf("invalid OSMOSIS_ROUTER %s, expected %s or %s", router, OsmosisRouterSQS, OsmosisRouterLocal)
+	}
+
+	denoms, err := NewDenomRegistry(conn)
+	if err != nil {
+		return SeedConfig{}, err
+	}
+
 	seedConfig = SeedConfig{
 		ChainID:        CHAIN_ID,
 		GRPCConnection: conn,
 		EncodingConfig: encCfg,
 		Key:            privKey,
+		Denoms:         denoms,
+		DenomMap:       make(map[string]string),
+		GasAdjustment:  gasAdjustment,
+		Router:         router,
 	}
 
 	return seedConfig, nil
@@ -0,0 +1,43 @@
+package src
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestCalculateTokenOutMinAmount(t *testing.T) {
+	tests := []struct {
+		name        string
+		quoted      int64
+		slippageBps uint64
+		breakeven   int64
+		want        int64
+		wantErr     bool
+	}{
+		{name: "zero tolerance", quoted: 1000, breakeven: 900, want: 1000},
+		{name: "tolerance on a whole amount", quoted: 1000, slippageBps: 50, want: 995},
+		{name: "tolerance rounded up", quoted: 999, slippageBps: 50, want: 995},
+		{name: "breakeven above the tolerance", quoted: 1000, slippageBps: 50, breakeven: 998, want: 998},
+		{name: "quote at breakeven", quoted: 1000, slippageBps: 100, breakeven: 1000, want: 1000},
+		{name: "quote below breakeven", quoted: 999, slippageBps: 50, breakeven: 1000, wantErr: true},
+		{name: "whole amount as tolerance", quoted: 1000, slippageBps: 10000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculateTokenOutMinAmount(sdk.NewInt(tt.quoted), tt.slippageBps, sdk.NewInt(tt.breakeven))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("calculateTokenOutMinAmount() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("calculateTokenOutMinAmount() = %v", err)
+			}
+			if !got.Equal(sdk.NewInt(tt.want)) {
+				t.Errorf("calculateTokenOutMinAmount() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
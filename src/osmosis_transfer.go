@@ -0,0 +1,89 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	transfertypes "github.com/cosmos/ibc-go/v7/modules/apps/transfer/types"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	txfeestypes "github.com/osmosis-labs/osmosis/v25/x/txfees/types"
+)
+
+// OsmosisAddress returns the bech32 address of the bot's Osmosis account
+func OsmosisAddress(seedConfig SeedConfig) string {
+	return sdk.AccAddress(seedConfig.Key.PubKey().Address()).String()
+}
+
+// GetOsmosisBalance returns the account's balance of denom in its smallest unit,
+// including the simulated swaps in dry run
+func GetOsmosisBalance(seedConfig SeedConfig, denom string) (sdk.Int, error) {
+	bankClient := banktypes.NewQueryClient(seedConfig.GRPCConnection)
+	res, err := bankClient.Balance(
+		context.Background(),
+		&banktypes.QueryBalanceRequest{Address: OsmosisAddress(seedConfig), Denom: denom},
+	)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+
+	amount := res.Balance.Amount
+	if seedConfig.DryRun {
+		amount = amount.Add(seedConfig.paperBalances.delta(denom))
+	}
+	return amount, nil
+}
+
+// IBCTransfer sends coin from the account over the transfer port of channel to
+// receiver on the counterparty chain. The packet times out ibcTransferTimeout
+// after it is sent, refunding the coin. onSubmitted, when set, is called with the
+// tx's hash right before it is broadcasted.
+func IBCTransfer(seedConfig SeedConfig, channel string, coin sdk.Coin, receiver, memo string, onSubmitted TxSubmittedFunc) (TxResult, error) {
+	grpcConnection := seedConfig.GRPCConnection
+	txClient := txtypes.NewServiceClient(grpcConnection)
+	ac := auth.NewQueryClient(grpcConnection)
+	tm := tmservice.NewServiceClient(grpcConnection)
+	txFeesClient := txfeestypes.NewQueryClient(grpcConnection)
+
+	transferMsg := transfertypes.NewMsgTransfer(
+		transfertypes.PortID,
+		channel,
+		coin,
+		OsmosisAddress(seedConfig),
+		receiver,
+		clienttypes.ZeroHeight(),
+		uint64(time.Now().Add(ibcTransferTimeout).UnixNano()),
+		memo,
+	)
+
+	gas, fee, err := estimateGasAndFee(seedConfig, ac, txClient, txFeesClient, []sdk.Msg{transferMsg})
+	if err != nil {
+		return TxResult{}, fmt.Errorf("error estimating transfer gas: %v", err)
+	}
+
+	if seedConfig.DryRun {
+		return simulateSignedSwap(seedConfig, tm, ac, txClient, []sdk.Msg{transferMsg}, gas, fee)
+	}
+
+	return SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		nil,
+		seedConfig.EncodingConfig,
+		tm,
+		ac,
+		txClient,
+		seedConfig.ChainID,
+		[]sdk.Msg{transferMsg},
+		[]uint64{},
+		gas,
+		fee,
+		onSubmitted,
+	)
+}
@@ -0,0 +1,133 @@
+package src
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type TxStatus string
+
+const (
+	// TxStatusIncluded means the tx was included in a block and executed successfully
+	TxStatusIncluded TxStatus = "included"
+	// TxStatusFailed means the tx was rejected by CheckTx or included with a non zero code
+	TxStatusFailed TxStatus = "failed"
+	// TxStatusExpired means the chain passed the tx's timeout height without including it
+	TxStatusExpired TxStatus = "expired"
+	// TxStatusAuctionLost means the top of block bid carrying the tx did not win its auction
+	TxStatusAuctionLost TxStatus = "auction_lost"
+	// TxStatusSimulated means the tx was signed and simulated but, in dry run, never broadcasted
+	TxStatusSimulated TxStatus = "simulated"
+)
+
+// TxResult is the final outcome of a broadcasted tx
+type TxResult struct {
+	TxHash  string
+	Status  TxStatus
+	Height  int64
+	Code    uint32
+	Log     string
+	GasUsed int64
+	Fee     sdk.Coins
+
+	// Data is the hex encoded sdk.TxMsgData holding the msg responses of an included tx
+	Data string
+}
+
+// TxSubmittedFunc is called with the hash and timeout height of a signed tx right
+// before it is broadcasted. Returning an error keeps the tx from being broadcasted.
+type TxSubmittedFunc func(hash string, timeoutHeight uint64) error
+
+// TxHash returns the hash a tx is indexed under by the node
+func TxHash(txBytes []byte) string {
+	hash := sha256.Sum256(txBytes)
+	return strings.ToUpper(fmt.Sprintf("%x", hash))
+}
+
+// WaitForTx polls the node until the tx with the given hash is included in a
+// block, or the chain moves past timeoutHeight without including it
+func WaitForTx(
+	ctx context.Context,
+	txClient txtypes.ServiceClient,
+	tm tmservice.ServiceClient,
+	hash string,
+	timeoutHeight uint64,
+) (TxResult, error) {
+	defer observeLatency("tx_confirmation", time.Now())
+
+	ticker := time.NewTicker(txConfirmPollInterval)
+	defer ticker.Stop()
+
+	for {
+		result, found, err := getTxResult(ctx, txClient, hash)
+		if err != nil {
+			return TxResult{}, err
+		}
+		if found {
+			return result, nil
+		}
+
+		block, err := tm.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+		if err != nil {
+			log.Println("Error fetching latest block while waiting for tx", hash, ":", err)
+		} else if uint64(block.Block.Header.Height) > timeoutHeight {
+			// the tx may have been indexed since the lookup above
+			result, found, err := getTxResult(ctx, txClient, hash)
+			if err != nil {
+				return TxResult{}, err
+			}
+			if found {
+				return result, nil
+			}
+			return TxResult{TxHash: hash, Status: TxStatusExpired}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return TxResult{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// getTxResult looks up an included tx, found is false while it is not indexed yet
+func getTxResult(ctx context.Context, txClient txtypes.ServiceClient, hash string) (result TxResult, found bool, err error) {
+	tx, err := txClient.GetTx(ctx, &txtypes.GetTxRequest{Hash: hash})
+	if err != nil {
+		if status.Code(err) == codes.NotFound || strings.Contains(err.Error(), "not found") {
+			return TxResult{}, false, nil
+		}
+		if ctx.Err() != nil {
+			return TxResult{}, false, ctx.Err()
+		}
+		log.Println("Error fetching tx", hash, ":", err)
+		return TxResult{}, false, nil
+	}
+
+	result = TxResult{
+		TxHash:  hash,
+		Status:  TxStatusIncluded,
+		Height:  tx.TxResponse.Height,
+		Code:    tx.TxResponse.Code,
+		Log:     tx.TxResponse.RawLog,
+		GasUsed: tx.TxResponse.GasUsed,
+		Data:    tx.TxResponse.Data,
+	}
+	if tx.Tx != nil && tx.Tx.AuthInfo != nil && tx.Tx.AuthInfo.Fee != nil {
+		result.Fee = tx.Tx.AuthInfo.Fee.Amount
+	}
+	if result.Code != 0 {
+		result.Status = TxStatusFailed
+	}
+	return result, true, nil
+}
@@ -21,6 +21,7 @@ import (
 
 	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 	authenticatortypes "github.com/osmosis-labs/osmosis/v25/x/smart-account/types"
+	txfeestypes "github.com/osmosis-labs/osmosis/v25/x/txfees/types"
 
 	"github.com/osmosis-labs/osmosis/v25/app/params"
 )
@@ -37,6 +38,8 @@ func SignAuthenticatorMsgMultiSignersBytes(
 	msgs []sdk.Msg,
 	selectedAuthenticators []uint64,
 	sequenceOffset uint64,
+	gas uint64,
+	feeAmt sdk.Coins,
 ) ([]byte, error) {
 	log.Println("Creating signed txn to include in bundle")
 
@@ -73,11 +76,11 @@ func SignAuthenticatorMsgMultiSignersBytes(
 	}
 
 	// Sign the message
-	txBytes, _ := SignAuthenticatorMsgWithHeight(
+	txBytes, err := SignAuthenticatorMsgWithHeight(
 		encCfg.TxConfig,
 		msgs,
-		sdk.Coins{sdk.NewInt64Coin(FEE_DENOM, 7000)},
-		1700000,
+		feeAmt,
+		gas,
 		chainID,
 		accNums,
 		accSeqs,
@@ -87,10 +90,107 @@ func SignAuthenticatorMsgMultiSignersBytes(
 		selectedAuthenticators,
 		uint64(block.Block.Header.Height)+1,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("error signing tx: %v", err)
+	}
 
 	return txBytes, nil
 }
 
+// SimulateAuthenticatorMsg simulates msgs sent by senderPrivKeys and returns the simulation result.
+// The simulated tx carries the signers' public keys but no signatures, so nothing is signed.
+func SimulateAuthenticatorMsg(
+	senderPrivKeys []cryptotypes.PrivKey,
+	encCfg params.EncodingConfig,
+	ac authtypes.QueryClient,
+	txClient txtypes.ServiceClient,
+	msgs []sdk.Msg,
+	selectedAuthenticators []uint64,
+) (*txtypes.SimulateResponse, error) {
+	sigs := make([]signing.SignatureV2, len(senderPrivKeys))
+	signMode := encCfg.TxConfig.SignModeHandler().DefaultMode()
+
+	for i, privKey := range senderPrivKeys {
+		addr := sdk.AccAddress(privKey.PubKey().Address()).String()
+
+		res, err := ac.Account(
+			context.Background(),
+			&authtypes.QueryAccountRequest{Address: addr},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var acc authtypes.AccountI
+		if err := encCfg.InterfaceRegistry.UnpackAny(res.Account, &acc); err != nil {
+			return nil, err
+		}
+
+		sigs[i] = signing.SignatureV2{
+			PubKey: privKey.PubKey(),
+			Data: &signing.SingleSignatureData{
+				SignMode: signMode,
+			},
+			Sequence: acc.GetSequence(),
+		}
+	}
+
+	baseTxBuilder := encCfg.TxConfig.NewTxBuilder()
+
+	txBuilder, ok := baseTxBuilder.(authtx.ExtensionOptionsTxBuilder)
+	if !ok {
+		return nil, fmt.Errorf("expected authtx.ExtensionOptionsTxBuilder, got %T", baseTxBuilder)
+	}
+	if len(selectedAuthenticators) > 0 {
+		value, err := types.NewAnyWithValue(&authenticatortypes.TxExtension{
+			SelectedAuthenticators: selectedAuthenticators,
+		})
+		if err != nil {
+			return nil, err
+		}
+		txBuilder.SetNonCriticalExtensionOptions(value)
+	}
+
+	if err := txBuilder.SetMsgs(msgs...); err != nil {
+		return nil, err
+	}
+	if err := txBuilder.SetSignatures(sigs...); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := encCfg.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, err
+	}
+
+	return SimulateTx(txClient, txBytes)
+}
+
+// SimulateTx simulates already encoded tx bytes, signed or not
+func SimulateTx(txClient txtypes.ServiceClient, txBytes []byte) (*txtypes.SimulateResponse, error) {
+	resp, err := txClient.Simulate(
+		context.Background(),
+		&txtypes.SimulateRequest{TxBytes: txBytes},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("simulation failed: %v", err)
+	}
+
+	log.Println("Simulated Gas Used:", resp.GasInfo.GasUsed)
+	return resp, nil
+}
+
+// GetFeeForGas returns the fee for gas at the chain's current EIP-1559 base fee, rounded up
+func GetFeeForGas(txFeesClient txfeestypes.QueryClient, gas uint64) (sdk.Coins, error) {
+	res, err := txFeesClient.GetEipBaseFee(context.Background(), &txfeestypes.QueryEipBaseFeeRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching base fee: %v", err)
+	}
+
+	feeAmount := res.BaseFee.MulInt64(int64(gas)).Ceil().TruncateInt()
+	return sdk.Coins{sdk.NewCoin(FEE_DENOM, feeAmount)}, nil
+}
+
 // GenTx generates a signed mock transaction.
 func SignAuthenticatorMsgWithHeight(
 	gen client.TxConfig,
@@ -228,7 +328,10 @@ func SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 	chainID string,
 	msgs []sdk.Msg,
 	selectedAuthenticators []uint64,
-) error {
+	gas uint64,
+	feeAmt sdk.Coins,
+	onSubmitted TxSubmittedFunc,
+) (TxResult, error) {
 	log.Println("Signing and broadcasting message flow")
 
 	var accNums []uint64
@@ -244,12 +347,12 @@ func SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 			&authtypes.QueryAccountRequest{Address: addr},
 		)
 		if err != nil {
-			return err
+			return TxResult{}, err
 		}
 
 		var acc authtypes.AccountI
 		if err := encCfg.InterfaceRegistry.UnpackAny(res.Account, &acc); err != nil {
-			return err
+			return TxResult{}, err
 		}
 
 		log.Println("Signer account: " + acc.GetAddress().String())
@@ -259,15 +362,16 @@ func SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 
 	block, err := tm.GetLatestBlock(context.Background(), &tmservice.GetLatestBlockRequest{})
 	if err != nil {
-		return err
+		return TxResult{}, err
 	}
 
 	// Sign the message
-	txBytes, _ := SignAuthenticatorMsgWithHeight(
+	timeoutHeight := uint64(block.Block.Header.Height) + 1
+	txBytes, err := SignAuthenticatorMsgWithHeight(
 		encCfg.TxConfig,
 		msgs,
-		sdk.Coins{sdk.NewInt64Coin(FEE_DENOM, 7000)},
-		1700000,
+		feeAmt,
+		gas,
 		chainID,
 		accNums,
 		accSeqs,
@@ -275,8 +379,17 @@ func SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 		signerPrivKeys,
 		cosignerPrivKeys,
 		selectedAuthenticators,
-		uint64(block.Block.Header.Height)+1,
+		timeoutHeight,
 	)
+	if err != nil {
+		return TxResult{}, fmt.Errorf("error signing tx: %v", err)
+	}
+
+	if onSubmitted != nil {
+		if err := onSubmitted(TxHash(txBytes), timeoutHeight); err != nil {
+			return TxResult{}, err
+		}
+	}
 
 	resp, err := txClient.BroadcastTx(
 		context.Background(),
@@ -286,31 +399,33 @@ func SignAndBroadcastAuthenticatorMsgMultiSignersWithBlock(
 		},
 	)
 	if err != nil {
-		return err
+		return TxResult{}, err
 	}
 	log.Println("Transaction Hash:", resp.TxResponse.TxHash)
-	if resp.TxResponse.RawLog != "" {
+	if resp.TxResponse.Code != 0 {
 		log.Println("Transaction failed reason:", resp.TxResponse.RawLog)
+		return TxResult{
+			TxHash: resp.TxResponse.TxHash,
+			Status: TxStatusFailed,
+			Code:   resp.TxResponse.Code,
+			Log:    resp.TxResponse.RawLog,
+		}, nil
 	}
 
-	time.Sleep(6 * time.Second)
-
-	tx, err := txClient.GetTx(
-		context.Background(),
-		&txtypes.GetTxRequest{
-			Hash: resp.TxResponse.TxHash,
-		},
-	)
+	result, err := WaitForTx(context.Background(), txClient, tm, resp.TxResponse.TxHash, timeoutHeight)
 	if err != nil {
-		return err
-	} else {
-		if tx.TxResponse.Code == 0 {
-			log.Println("Transaction Success...")
-		} else {
-			log.Println(tx.TxResponse)
-		}
+		return TxResult{}, err
+	}
+
+	switch result.Status {
+	case TxStatusIncluded:
+		log.Println("Transaction Success...")
+		log.Println("Gas Used:", result.GasUsed)
+	case TxStatusFailed:
+		log.Println("Transaction failed with code", result.Code, ":", result.Log)
+	default:
+		log.Println("Transaction", result.Status)
 	}
-	log.Println("Gas Used:", tx.TxResponse.GasUsed)
 
-	return nil
+	return result, nil
 }
@@ -0,0 +1,309 @@
+package src
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+)
+
+// EnableDryRun makes swaps get signed and simulated instead of broadcasted, with
+// what they would have moved tracked in memory on top of the on-chain balances
+func (s *SeedConfig) EnableDryRun() {
+	s.DryRun = true
+	s.paperBalances = &paperBalances{deltas: make(map[string]sdk.Int)}
+}
+
+// paperBalances holds the balance changes of the swaps simulated in dry run
+type paperBalances struct {
+	mu     sync.Mutex
+	deltas map[string]sdk.Int
+}
+
+func (p *paperBalances) delta(denom string) sdk.Int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if delta, ok := p.deltas[denom]; ok {
+		return delta
+	}
+	return sdk.ZeroInt()
+}
+
+func (p *paperBalances) add(denom string, amount sdk.Int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delta, ok := p.deltas[denom]
+	if !ok {
+		delta = sdk.ZeroInt()
+	}
+	p.deltas[denom] = delta.Add(amount)
+}
+
+// apply books a simulated swap, along with its fees and bid
+func (p *paperBalances) apply(result SwapResult) {
+	p.add(result.TokenIn.Denom, result.TokenIn.Amount.Neg())
+	p.add(result.TokenOut.Denom, result.TokenOut.Amount)
+	for _, fee := range result.Fees {
+		p.add(fee.Denom, fee.Amount.Neg())
+	}
+	if result.AuctionBid.IsValid() && !result.AuctionBid.IsZero() {
+		p.add(result.AuctionBid.Denom, result.AuctionBid.Amount.Neg())
+	}
+}
+
+// simulateSignedSwap signs msgs at the account's current sequence and simulates
+// them in place of broadcasting
+func simulateSignedSwap(
+	seedConfig SeedConfig,
+	tm tmservice.ServiceClient,
+	ac auth.QueryClient,
+	txClient txtypes.ServiceClient,
+	msgs []sdk.Msg,
+	gas uint64,
+	fee sdk.Coins,
+) (TxResult, error) {
+	txBytes, err := SignAuthenticatorMsgMultiSignersBytes(
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		[]cryptotypes.PrivKey{seedConfig.Key},
+		nil,
+		seedConfig.EncodingConfig,
+		tm,
+		ac,
+		txClient,
+		seedConfig.ChainID,
+		msgs,
+		[]uint64{},
+		0,
+		gas,
+		fee,
+	)
+	if err != nil {
+		return TxResult{}, err
+	}
+
+	simulation, err := SimulateTx(txClient, txBytes)
+	if err != nil {
+		return TxResult{}, err
+	}
+
+	return simulatedTxResult(TxHash(txBytes), simulation, fee)
+}
+
+// simulatedTxResult reports a simulation the way WaitForTx reports an included tx
+func simulatedTxResult(hash string, simulation *txtypes.SimulateResponse, fee sdk.Coins) (TxResult, error) {
+	msgData := sdk.TxMsgData{MsgResponses: simulation.Result.MsgResponses}
+	bz, err := msgData.Marshal()
+	if err != nil {
+		return TxResult{}, err
+	}
+
+	return TxResult{
+		TxHash:  hash,
+		Status:  TxStatusSimulated,
+		Log:     simulation.Result.Log,
+		GasUsed: int64(simulation.GasInfo.GasUsed),
+		Fee:     fee,
+		Data:    hex.EncodeToString(bz),
+	}, nil
+}
+
+// PaperVenue trades on paper against a live venue. Market data comes from the
+// wrapped venue, orders fill against its current order book and only move
+// balances kept in memory, seeded from the wrapped venue's balances.
+type PaperVenue struct {
+	venue CEXVenue
+
+	mu       sync.Mutex
+	markets  map[string]TradingPair
+	balances map[string]sdk.Dec
+	orders   map[string]OrderResult
+	nextID   int64
+}
+
+var (
+	_ CEXVenue          = (*PaperVenue)(nil)
+	_ BookTickerWatcher = (*PaperVenue)(nil)
+)
+
+// NewPaperVenue paper trades the CEX symbols of pairs on top of venue
+func NewPaperVenue(venue CEXVenue, pairs []TradingPair) *PaperVenue {
+	markets := make(map[string]TradingPair, len(pairs))
+	for _, pair := range pairs {
+		markets[pair.CEXSymbol] = pair
+	}
+
+	return &PaperVenue{
+		venue:    venue,
+		markets:  markets,
+		balances: make(map[string]sdk.Dec),
+		orders:   make(map[string]OrderResult),
+	}
+}
+
+func (p *PaperVenue) Name() string {
+	return p.venue.Name()
+}
+
+func (p *PaperVenue) GetPrice(symbol string) (sdk.Dec, error) {
+	return p.venue.GetPrice(symbol)
+}
+
+func (p *PaperVenue) GetDepth(symbol string, limit int) (OrderBook, error) {
+	return p.venue.GetDepth(symbol, limit)
+}
+
+func (p *PaperVenue) GetSymbolFilters(symbol string) (SymbolFilters, error) {
+	return p.venue.GetSymbolFilters(symbol)
+}
+
+func (p *PaperVenue) GetTradingFees(symbol string) (TradingFees, error) {
+	return p.venue.GetTradingFees(symbol)
+}
+
+func (p *PaperVenue) GetBalances(assets []string) (map[string]Balance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.seedBalances(assets); err != nil {
+		return nil, err
+	}
+
+	// paper orders never rest, nothing is locked
+	balances := make(map[string]Balance, len(assets))
+	for _, asset := range assets {
+		balances[asset] = NewBalance(p.balances[asset], sdk.ZeroDec())
+	}
+	return balances, nil
+}
+
+// seedBalances fetches the starting balance of the assets not seen yet, must be called with mu held
+func (p *PaperVenue) seedBalances(assets []string) error {
+	var missing []string
+	for _, asset := range assets {
+		if _, ok := p.balances[asset]; !ok {
+			missing = append(missing, asset)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	balances, err := p.venue.GetBalances(missing)
+	if err != nil {
+		return err
+	}
+	for _, asset := range missing {
+		// the paper account starts from the free balance
+		p.balances[asset] = NewBalance(balances[asset].Free, sdk.ZeroDec()).Free
+	}
+	return nil
+}
+
+// PlaceOrder fills the order against the wrapped venue's current book, see OrderBook.Fill
+func (p *PaperVenue) PlaceOrder(order OrderRequest) (OrderResult, error) {
+	market, ok := p.markets[order.Symbol]
+	if !ok {
+		return OrderResult{}, fmt.Errorf("unknown symbol %s", order.Symbol)
+	}
+	if !order.Quantity.IsPositive() {
+		return OrderResult{}, fmt.Errorf("invalid order quantity %s", order.Quantity)
+	}
+
+	book, err := p.venue.GetDepth(order.Symbol, cexDepthLimit)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	filled, price, err := book.Fill(order)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	filters, err := p.venue.GetSymbolFilters(order.Symbol)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	if err := filters.ForOrderType(order.Type).CheckOrder(order.Quantity, filterPrice(order, price)); err != nil {
+		return OrderResult{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.seedBalances([]string{market.CEXBaseAsset, market.CEXQuoteAsset}); err != nil {
+		return OrderResult{}, err
+	}
+
+	quoteAmount := filled.Mul(price)
+	baseBalance, quoteBalance := p.balances[market.CEXBaseAsset], p.balances[market.CEXQuoteAsset]
+	switch order.Side {
+	case OrderSideBuy:
+		if quoteBalance.LT(quoteAmount) {
+			return OrderResult{}, fmt.Errorf("insufficient %s balance", market.CEXQuoteAsset)
+		}
+		p.balances[market.CEXQuoteAsset] = quoteBalance.Sub(quoteAmount)
+		p.balances[market.CEXBaseAsset] = baseBalance.Add(filled)
+	case OrderSideSell:
+		if baseBalance.LT(order.Quantity) {
+			return OrderResult{}, fmt.Errorf("insufficient %s balance", market.CEXBaseAsset)
+		}
+		p.balances[market.CEXBaseAsset] = baseBalance.Sub(filled)
+		p.balances[market.CEXQuoteAsset] = quoteBalance.Add(quoteAmount)
+	default:
+		return OrderResult{}, fmt.Errorf("invalid order side %s", order.Side)
+	}
+
+	p.nextID++
+	result := OrderResult{
+		OrderID:          "paper-" + strconv.FormatInt(p.nextID, 10),
+		ClientOrderID:    order.ClientOrderID,
+		Symbol:           order.Symbol,
+		Side:             order.Side,
+		Status:           filledOrderStatus(order, filled),
+		ExecutedQuantity: filled,
+		Price:            price,
+		Commissions:      map[string]sdk.Dec{},
+	}
+	p.orders[result.OrderID] = result
+
+	fmt.Println("Paper", order.Type, order.Side, order.Quantity, order.Symbol, "filled", filled, "at", price)
+	return result, nil
+}
+
+func (p *PaperVenue) CancelOrder(symbol, orderID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order, err := findOrder(p.orders, symbol, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != OrderStatusNew && order.Status != OrderStatusPartiallyFilled {
+		return fmt.Errorf("order %s is already %s", orderID, order.Status)
+	}
+
+	order.Status = OrderStatusCanceled
+	p.orders[order.OrderID] = order
+	return nil
+}
+
+func (p *PaperVenue) GetOrderStatus(symbol, orderID string) (OrderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return findOrder(p.orders, symbol, orderID)
+}
+
+// WatchBookTicker forwards the wrapped venue's book updates, if it streams any
+func (p *PaperVenue) WatchBookTicker(ctx context.Context, symbols []string, onUpdate func(symbol string)) {
+	if watcher, ok := p.venue.(BookTickerWatcher); ok {
+		watcher.WatchBookTicker(ctx, symbols, onUpdate)
+	}
+}
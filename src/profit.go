@@ -0,0 +1,126 @@
+package src
+
+import (
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// arbProfit itemizes the expected profit of an arb, in human readable units of
+// the pair's quote asset
+type arbProfit struct {
+	// gross is what the price difference between the venues makes, with the
+	// Osmosis leg priced before the pools' fees
+	gross sdk.Dec
+	// cexFee is the commission on the hedge, poolFees the spread factors and
+	// taker fees the route's pools charge
+	cexFee   sdk.Dec
+	poolFees sdk.Dec
+	// gas and auctionBid do not depend on the size, they are only estimated for
+	// the size traded, see estimateFixedCosts
+	gas        sdk.Dec
+	auctionBid sdk.Dec
+	// notional is the hedge's value, the base of the bps figures
+	notional sdk.Dec
+}
+
+// newArbProfit prices the gross profit and the fees of an arb hedging amount in
+// direction at cexPrice. osmosisNotional is the quote the Osmosis leg returns
+// for amount, or spends on it, net of the pools' fees charged at osmosisFeeRate
+// of the amount in, and the hedge pays cexFeeRate of its notional.
+func newArbProfit(direction ArbDirection, amount, cexPrice, osmosisNotional, osmosisFeeRate, cexFeeRate sdk.Dec) arbProfit {
+	if !isSetDec(osmosisFeeRate) || osmosisFeeRate.GTE(sdk.OneDec()) {
+		osmosisFeeRate = sdk.ZeroDec()
+	}
+	cexNotional := amount.Mul(cexPrice)
+
+	profit := arbProfit{
+		cexFee:     cexNotional.Mul(cexFeeRate),
+		gas:        sdk.ZeroDec(),
+		auctionBid: sdk.ZeroDec(),
+		notional:   cexNotional,
+	}
+	switch direction {
+	case ArbDirectionBuyCEX:
+		// the fees came out of the quote the swap returned
+		profit.poolFees = osmosisNotional.Mul(osmosisFeeRate).Quo(sdk.OneDec().Sub(osmosisFeeRate))
+		profit.gross = osmosisNotional.Add(profit.poolFees).Sub(cexNotional)
+	case ArbDirectionSellCEX:
+		// the fees came out of the quote the swap spent
+		profit.poolFees = osmosisNotional.Mul(osmosisFeeRate)
+		profit.gross = cexNotional.Sub(osmosisNotional.Sub(profit.poolFees))
+	default:
+		profit.poolFees, profit.gross = sdk.ZeroDec(), sdk.ZeroDec()
+	}
+	return profit
+}
+
+// variable returns the profit after the costs that grow with the size
+func (p arbProfit) variable() sdk.Dec {
+	return p.gross.Sub(p.cexFee).Sub(p.poolFees)
+}
+
+// net returns the profit after every cost
+func (p arbProfit) net() sdk.Dec {
+	return p.variable().Sub(p.gas).Sub(p.auctionBid)
+}
+
+// netBps returns the net profit in bps of the notional
+func (p arbProfit) netBps() sdk.Dec {
+	if !p.notional.IsPositive() {
+		return sdk.ZeroDec()
+	}
+	return p.net().MulInt64(10_000).Quo(p.notional)
+}
+
+// clears reports whether the net profit meets both of the pair's minimums
+func (p arbProfit) clears(pair TradingPair) bool {
+	return p.net().IsPositive() &&
+		p.net().GTE(floatToDec(pair.MinProfit)) &&
+		p.netBps().GTE(floatToDec(pair.MinProfitBps))
+}
+
+func (p arbProfit) String() string {
+	return fmt.Sprintf("gross %s - cex fee %s - pool fees %s - gas %s - auction bid %s = %s (%s bps)",
+		p.gross, p.cexFee, p.poolFees, p.gas, p.auctionBid, p.net(), p.netBps())
+}
+
+// estimateFixedCosts values the gas the candidate's swap would use and the
+// auction bid it would make in the pair's quote asset. The bid is sized from
+// the profit left after gas and kept on the candidate for the swap to send.
+func estimateFixedCosts(seedConfig SeedConfig, pair TradingPair, candidate *arbCandidate) error {
+	tokenInDenom := pair.BaseDenom
+	if candidate.direction == ArbDirectionSellCEX {
+		tokenInDenom = pair.QuoteDenom
+	}
+
+	fee, err := seedConfig.osmosis().EstimateSwapFee(candidate.osmosisQuote.Route, tokenInDenom)
+	if err != nil {
+		return fmt.Errorf("error estimating swap fee: %v", err)
+	}
+	gas := sdk.ZeroDec()
+	for _, coin := range fee {
+		value, err := convertDenomToQuote(seedConfig, pair, coin)
+		if err != nil {
+			return fmt.Errorf("error valuing %s in %s: %v", coin, pair.CEXQuoteAsset, err)
+		}
+		gas = gas.Add(value)
+	}
+	candidate.profit.gas = gas
+
+	bid, ok, err := CalculateAuctionBid(seedConfig, pair, candidate.bidProfit())
+	if err != nil {
+		return err
+	}
+	candidate.profit.auctionBid = sdk.ZeroDec()
+	candidate.auctionBid = sdk.Coin{}
+	if ok {
+		candidate.auctionBid = bid
+		value, err := convertDenomToQuote(seedConfig, pair, bid)
+		if err != nil {
+			return fmt.Errorf("error valuing %s in %s: %v", bid, pair.CEXQuoteAsset, err)
+		}
+		candidate.profit.auctionBid = value
+	}
+	return nil
+}
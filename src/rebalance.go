@@ -0,0 +1,322 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// TransferDirection is which way a transfer moves inventory
+type TransferDirection string
+
+const (
+	// TransferToOsmosis withdraws from the CEX to the Osmosis account
+	TransferToOsmosis TransferDirection = "cex_to_osmosis"
+	// TransferToCEX sends from the Osmosis account to the CEX deposit address over IBC
+	TransferToCEX TransferDirection = "osmosis_to_cex"
+)
+
+// Transfer is a movement of inventory between the CEX and Osmosis, journaled
+// and tracked until it lands. Amount is in human readable units of the asset.
+type Transfer struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Asset     string            `json:"asset"`
+	Denom     string            `json:"denom"`
+	Direction TransferDirection `json:"direction"`
+	Amount    sdk.Dec           `json:"amount"`
+	Status    TransferStatus    `json:"status"`
+
+	// Network, Address and Memo are where the funds are sent, on the CEX side
+	// the network they are withdrawn or deposited over
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Memo    string `json:"memo,omitempty"`
+
+	// WithdrawalTxHash is the on-chain tx of a CEX withdrawal
+	WithdrawalTxHash string `json:"withdrawal_tx_hash,omitempty"`
+
+	// the IBC transfer of a transfer to the CEX
+	Channel       string   `json:"channel,omitempty"`
+	TxHash        string   `json:"tx_hash,omitempty"`
+	TimeoutHeight uint64   `json:"timeout_height,omitempty"`
+	TxStatus      TxStatus `json:"tx_status,omitempty"`
+
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// RunRebalancer rebalances inventory every interval of config until ctx is
+// cancelled. It does nothing without assets to rebalance or when venue can't
+// move funds.
+func RunRebalancer(ctx context.Context, seedConfig SeedConfig, venue CEXVenue, config RebalanceConfig, journal *Journal) {
+	if len(config.Assets) == 0 {
+		return
+	}
+	transferVenue, ok := venue.(TransferVenue)
+	if !ok {
+		log.Println(venue.Name(), "can't transfer funds, inventory is not rebalanced")
+		return
+	}
+	if journal == nil {
+		log.Println("No journal to track transfers in, inventory is not rebalanced")
+		return
+	}
+
+	ticker := time.NewTicker(config.Interval())
+	defer ticker.Stop()
+
+	for {
+		if err := Rebalance(seedConfig, venue, transferVenue, config, journal); err != nil {
+			log.Println("Error rebalancing inventory:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebalance tracks the pending transfers, then starts a transfer for every asset
+// whose share of inventory on Osmosis drifted past its threshold. An asset is
+// not rebalanced again while a transfer of it is pending. It waits for any arb
+// being evaluated to finish, and arbs wait for it.
+func Rebalance(seedConfig SeedConfig, venue CEXVenue, transferVenue TransferVenue, config RebalanceConfig, journal *Journal) error {
+	osmosisAccountMu.Lock()
+	defer osmosisAccountMu.Unlock()
+
+	pending, err := journal.PendingTransfers()
+	if err != nil {
+		return fmt.Errorf("error reading pending transfers: %v", err)
+	}
+
+	var errs []error
+	busy := make(map[string]bool)
+	for i := range pending {
+		transfer := &pending[i]
+		if err := trackTransfer(seedConfig, transferVenue, transfer); err != nil {
+			errs = append(errs, fmt.Errorf("transfer %s: %w", transfer.ID, err))
+		}
+		if err := journal.RecordTransfer(*transfer); err != nil {
+			errs = append(errs, fmt.Errorf("error journaling transfer %s: %v", transfer.ID, err))
+		}
+		if transfer.Status == TransferStatusPending {
+			busy[transfer.Asset] = true
+		}
+	}
+
+	for _, asset := range config.Assets {
+		if busy[asset.CEXAsset] {
+			continue
+		}
+		if err := rebalanceAsset(seedConfig, venue, transferVenue, asset, journal); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", asset.CEXAsset, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rebalanceAsset moves the inventory of asset back to its target share on
+// Osmosis once it drifted past the asset's threshold
+func rebalanceAsset(seedConfig SeedConfig, venue CEXVenue, transferVenue TransferVenue, asset RebalanceAsset, journal *Journal) error {
+	cexBalances, err := venue.GetBalances([]string{asset.CEXAsset})
+	if err != nil {
+		return fmt.Errorf("error fetching %s balance: %v", venue.Name(), err)
+	}
+	balance, ok := cexBalances[asset.CEXAsset]
+	if !ok {
+		return fmt.Errorf("%s returned no balance", venue.Name())
+	}
+	// orders resting on the CEX still count towards its inventory
+	cexBalance := balance.Total()
+
+	osmosisAmount, err := GetOsmosisBalance(seedConfig, asset.Denom)
+	if err != nil {
+		return fmt.Errorf("error fetching Osmosis balance: %v", err)
+	}
+	osmosisBalance := fromBaseUnits(osmosisAmount, asset.Exponent)
+
+	total := cexBalance.Add(osmosisBalance)
+	if !total.IsPositive() {
+		return nil
+	}
+	share := osmosisBalance.Quo(total)
+	inventoryShare.WithLabelValues(asset.CEXAsset).Set(decToFloat(share))
+
+	drift := share.Sub(floatToDec(asset.OsmosisShare))
+	if drift.Abs().LTE(floatToDec(asset.Threshold)) {
+		return nil
+	}
+
+	// amounts go out in the denom's smallest unit and the CEX's withdrawal precision
+	amount := truncateDec(drift.Abs().Mul(total), min(asset.Exponent, binanceQuantityDecimals))
+	if !amount.IsPositive() || amount.LT(floatToDec(asset.MinTransfer)) {
+		fmt.Println("Osmosis holds", share, "of", asset.CEXAsset, "but a transfer of", amount, "is below the minimum")
+		return nil
+	}
+
+	transfer := Transfer{
+		ID:     fmt.Sprintf("rebalance%d", time.Now().UnixNano()),
+		Time:   time.Now(),
+		Asset:  asset.CEXAsset,
+		Denom:  asset.Denom,
+		Amount: amount,
+		Status: TransferStatusPending,
+	}
+	fmt.Println("Osmosis holds", share, "of", asset.CEXAsset, "against a target of", asset.OsmosisShare, ", transferring", amount)
+
+	if drift.IsNegative() {
+		return withdrawToOsmosis(seedConfig, transferVenue, asset, transfer, journal)
+	}
+	return depositToCEX(seedConfig, transferVenue, asset, transfer, journal)
+}
+
+// withdrawToOsmosis withdraws the transfer from the CEX to the Osmosis account
+func withdrawToOsmosis(seedConfig SeedConfig, transferVenue TransferVenue, asset RebalanceAsset, transfer Transfer, journal *Journal) error {
+	transfer.Direction = TransferToOsmosis
+	transfer.Network = asset.WithdrawNetwork
+	transfer.Address = OsmosisAddress(seedConfig)
+
+	// journaled first so a withdrawal that went through is tracked even if the request errors
+	if err := journal.RecordTransfer(transfer); err != nil {
+		return fmt.Errorf("error journaling transfer %s: %v", transfer.ID, err)
+	}
+	transfers.WithLabelValues(transfer.Asset, string(transfer.Direction), string(TransferStatusPending)).Inc()
+
+	err := transferVenue.Withdraw(WithdrawRequest{
+		ID:      transfer.ID,
+		Asset:   transfer.Asset,
+		Network: transfer.Network,
+		Address: transfer.Address,
+		Amount:  transfer.Amount,
+	})
+	if err != nil {
+		transfer.Error = err.Error()
+		if journalErr := journal.RecordTransfer(transfer); journalErr != nil {
+			fmt.Println("Error journaling transfer", transfer.ID, ":", journalErr)
+		}
+		return fmt.Errorf("error withdrawing %s %s: %v", transfer.Amount, transfer.Asset, err)
+	}
+	return nil
+}
+
+// depositToCEX sends the transfer over IBC to the CEX's deposit address
+func depositToCEX(seedConfig SeedConfig, transferVenue TransferVenue, asset RebalanceAsset, transfer Transfer, journal *Journal) error {
+	address, memo, err := transferVenue.GetDepositAddress(asset.CEXAsset, asset.DepositNetwork)
+	if err != nil {
+		return fmt.Errorf("error fetching %s deposit address: %v", asset.DepositNetwork, err)
+	}
+
+	transfer.Direction = TransferToCEX
+	transfer.Network = asset.DepositNetwork
+	transfer.Address = address
+	transfer.Memo = memo
+	transfer.Channel = asset.IBCChannel
+
+	coin := sdk.NewCoin(asset.Denom, toBaseUnits(transfer.Amount, asset.Exponent))
+	result, err := IBCTransfer(seedConfig, asset.IBCChannel, coin, address, memo, func(hash string, timeoutHeight uint64) error {
+		transfer.TxHash = hash
+		transfer.TimeoutHeight = timeoutHeight
+		if err := journal.RecordTransfer(transfer); err != nil {
+			return err
+		}
+		transfers.WithLabelValues(transfer.Asset, string(transfer.Direction), string(TransferStatusPending)).Inc()
+		return nil
+	})
+	if err != nil {
+		if transfer.TxHash == "" {
+			return fmt.Errorf("error sending %s over %s: %v", coin, asset.IBCChannel, err)
+		}
+		// the tx may have been broadcasted, tracking looks it up
+		transfer.Error = err.Error()
+	} else {
+		applyTransferTx(&transfer, result)
+	}
+
+	if err := journal.RecordTransfer(transfer); err != nil {
+		return fmt.Errorf("error journaling transfer %s: %v", transfer.ID, err)
+	}
+	if transfer.Status == TransferStatusFailed {
+		return fmt.Errorf("ibc transfer of %s failed: %s", coin, transfer.Error)
+	}
+	return nil
+}
+
+// applyTransferTx records the outcome of the IBC transfer tx
+func applyTransferTx(transfer *Transfer, result TxResult) {
+	transfer.TxStatus = result.Status
+	if result.Status != TxStatusIncluded {
+		setTransferStatus(transfer, TransferStatusFailed)
+		transfer.Error = fmt.Sprintf("ibc transfer tx %s %s: %s", result.TxHash, result.Status, result.Log)
+	}
+}
+
+// trackTransfer updates a pending transfer from the CEX and the chain
+func trackTransfer(seedConfig SeedConfig, transferVenue TransferVenue, transfer *Transfer) error {
+	switch transfer.Direction {
+	case TransferToOsmosis:
+		status, txHash, err := transferVenue.GetWithdrawal(transfer.Asset, transfer.ID)
+		if errors.Is(err, ErrTransferNotFound) && time.Since(transfer.Time) > transferLookupGrace {
+			transfer.Error = err.Error()
+			setTransferStatus(transfer, TransferStatusFailed)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		transfer.WithdrawalTxHash = txHash
+		setTransferStatus(transfer, status)
+
+	case TransferToCEX:
+		// the tx of a transfer interrupted by a restart is looked up first
+		if transfer.TxStatus == "" {
+			result, err := waitForOsmosisTx(context.Background(), seedConfig, transfer.TxHash, transfer.TimeoutHeight)
+			if err != nil {
+				return err
+			}
+			applyTransferTx(transfer, result)
+			if transfer.Status != TransferStatusPending {
+				return nil
+			}
+		}
+
+		status, found, err := transferVenue.FindDeposit(transfer.Asset, transfer.Address, transfer.Memo, transfer.Amount, transfer.Time)
+		if err != nil {
+			return err
+		}
+		if found {
+			setTransferStatus(transfer, status)
+		} else if time.Since(transfer.Time) > transferDepositTimeout {
+			// the packet timed out and was refunded, or the deposit went astray
+			transfer.Error = fmt.Sprintf("not credited after %s", transferDepositTimeout)
+			setTransferStatus(transfer, TransferStatusFailed)
+		}
+
+	default:
+		return fmt.Errorf("invalid transfer direction %s", transfer.Direction)
+	}
+	return nil
+}
+
+// setTransferStatus moves a transfer to status, logging when it settles
+func setTransferStatus(transfer *Transfer, status TransferStatus) {
+	if status == transfer.Status {
+		return
+	}
+	transfer.Status = status
+	transfers.WithLabelValues(transfer.Asset, string(transfer.Direction), string(status)).Inc()
+
+	switch status {
+	case TransferStatusCompleted:
+		transfer.CompletedAt = time.Now()
+		fmt.Println("Transfer", transfer.ID, "of", transfer.Amount, transfer.Asset, transfer.Direction, "landed")
+	case TransferStatusFailed:
+		fmt.Println("Transfer", transfer.ID, "of", transfer.Amount, transfer.Asset, transfer.Direction, "failed:", transfer.Error)
+	}
+}
@@ -0,0 +1,407 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/osmosis-labs/osmosis/osmomath"
+
+	clqueryproto "github.com/osmosis-labs/osmosis/v25/x/concentrated-liquidity/client/queryproto"
+	clmath "github.com/osmosis-labs/osmosis/v25/x/concentrated-liquidity/math"
+	clmodel "github.com/osmosis-labs/osmosis/v25/x/concentrated-liquidity/model"
+	"github.com/osmosis-labs/osmosis/v25/x/concentrated-liquidity/swapstrategy"
+	gammtypes "github.com/osmosis-labs/osmosis/v25/x/gamm/types"
+	"github.com/osmosis-labs/osmosis/v25/x/poolmanager"
+	poolmanagerqueryproto "github.com/osmosis-labs/osmosis/v25/x/poolmanager/client/queryproto"
+	poolmanagertypes "github.com/osmosis-labs/osmosis/v25/x/poolmanager/types"
+)
+
+// localRouter quotes swaps from pool state loaded from our node. It is meant to
+// serve a single quote, so the state it loads is never stale by more than that.
+type localRouter struct {
+	seedConfig SeedConfig
+	poolClient poolmanagerqueryproto.QueryClient
+	clClient   clqueryproto.QueryClient
+
+	pools     map[uint64]poolmanagertypes.PoolI
+	ticks     map[string]*clqueryproto.LiquidityNetInDirectionResponse
+	takerFees map[string]osmomath.Dec
+}
+
+// localRoute is a path of pools from one denom to another
+type localRoute []poolmanagertypes.SwapAmountInRoute
+
+func newLocalRouter(seedConfig SeedConfig) *localRouter {
+	return &localRouter{
+		seedConfig: seedConfig,
+		poolClient: poolmanagerqueryproto.NewQueryClient(seedConfig.GRPCConnection),
+		clClient:   clqueryproto.NewQueryClient(seedConfig.GRPCConnection),
+		pools:      make(map[uint64]poolmanagertypes.PoolI),
+		ticks:      make(map[string]*clqueryproto.LiquidityNetInDirectionResponse),
+		takerFees:  make(map[string]osmomath.Dec),
+	}
+}
+
+// getLocalPriceAndRoute is the local router's counterpart of getSQSPriceAndRoute.
+// It considers direct and two hop routes through balancer, stableswap and
+// concentrated liquidity pools and splits the amount in across the best of them.
+func getLocalPriceAndRoute(seedConfig SeedConfig, tokenInDenom, tokenOutDenom string, tokenInAmount sdk.Int) (OsmosisQuote, error) {
+	defer observeLatency("local_router_quote", time.Now())
+
+	if !tokenInAmount.IsPositive() {
+		return OsmosisQuote{}, fmt.Errorf("invalid amount in %s", tokenInAmount)
+	}
+
+	router := newLocalRouter(seedConfig)
+	routes, err := router.candidateRoutes(tokenInDenom, tokenOutDenom)
+	if err != nil {
+		return OsmosisQuote{}, err
+	}
+
+	tokenIn := sdk.NewCoin(tokenInDenom, tokenInAmount)
+	route, amountOut, err := router.splitRoutes(tokenIn, routes)
+	if err != nil {
+		return OsmosisQuote{}, err
+	}
+	feeRate, err := router.routeFeeRate(tokenInDenom, route)
+	if err != nil {
+		return OsmosisQuote{}, err
+	}
+
+	return OsmosisQuote{
+		Price:          sdk.NewDecFromInt(amountOut).QuoTruncate(sdk.NewDecFromInt(tokenInAmount)),
+		TokenInAmount:  tokenIn.Amount,
+		TokenOutAmount: amountOut,
+		Route:          route,
+		FeeRate:        feeRate,
+	}, nil
+}
+
+// candidateRoutes returns the direct and two hop routes between the denoms, only
+// through pools the router can compute swaps for
+func (r *localRouter) candidateRoutes(tokenInDenom, tokenOutDenom string) ([]localRoute, error) {
+	inPools, err := r.loadPoolsByDenom(tokenInDenom)
+	if err != nil {
+		return nil, err
+	}
+	outPools, err := r.loadPoolsByDenom(tokenOutDenom)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []localRoute
+	for _, pool := range inPools {
+		if poolHasDenom(pool, tokenOutDenom) {
+			routes = append(routes, localRoute{{PoolId: pool.GetId(), TokenOutDenom: tokenOutDenom}})
+		}
+	}
+
+	intermediates := r.seedConfig.RouteIntermediateDenoms
+	if len(intermediates) == 0 {
+		intermediates = []string{FEE_DENOM}
+	}
+	for _, intermediate := range intermediates {
+		if intermediate == tokenInDenom || intermediate == tokenOutDenom {
+			continue
+		}
+		for _, first := range inPools {
+			if !poolHasDenom(first, intermediate) {
+				continue
+			}
+			for _, second := range outPools {
+				if second.GetId() == first.GetId() || !poolHasDenom(second, intermediate) {
+					continue
+				}
+				routes = append(routes, localRoute{
+					{PoolId: first.GetId(), TokenOutDenom: intermediate},
+					{PoolId: second.GetId(), TokenOutDenom: tokenOutDenom},
+				})
+			}
+		}
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no route from %s to %s", tokenInDenom, tokenOutDenom)
+	}
+	return routes, nil
+}
+
+// splitRoutes splits tokenIn across the routes returning the most for a slice of
+// the amount, giving each slice to the route with the best marginal output.
+// Routes too shallow for the whole amount still take the slices they can.
+func (r *localRouter) splitRoutes(tokenIn sdk.Coin, routes []localRoute) ([]poolmanagertypes.SwapAmountInSplitRoute, sdk.Int, error) {
+	type rankedRoute struct {
+		route     localRoute
+		amountOut sdk.Int
+	}
+
+	step := tokenIn.Amount.QuoRaw(localRouterSplitSteps)
+	if !step.IsPositive() {
+		step = tokenIn.Amount
+	}
+
+	var ranked []rankedRoute
+	for _, route := range routes {
+		amountOut, err := r.routeAmountOut(sdk.NewCoin(tokenIn.Denom, step), route)
+		if err != nil {
+			// a route that can't take a single slice has nothing to contribute
+			continue
+		}
+		ranked = append(ranked, rankedRoute{route: route, amountOut: amountOut})
+	}
+	if len(ranked) == 0 {
+		return nil, sdk.Int{}, fmt.Errorf("no route from %s to %s can take a slice of %s", tokenIn.Denom, routes[0][len(routes[0])-1].TokenOutDenom, tokenIn)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].amountOut.GT(ranked[j].amountOut)
+	})
+	if len(ranked) > localRouterMaxSplitRoutes {
+		ranked = ranked[:localRouterMaxSplitRoutes]
+	}
+
+	amountsIn := make([]sdk.Int, len(ranked))
+	amountsOut := make([]sdk.Int, len(ranked))
+	for i := range ranked {
+		amountsIn[i] = sdk.ZeroInt()
+		amountsOut[i] = sdk.ZeroInt()
+	}
+
+	remaining := tokenIn.Amount
+	for remaining.IsPositive() {
+		slice := sdk.MinInt(step, remaining)
+		if !slice.IsPositive() || remaining.Sub(slice).LT(step) {
+			// the last slice takes the rounding remainder along
+			slice = remaining
+		}
+
+		best := -1
+		bestGain := sdk.ZeroInt()
+		var bestOut sdk.Int
+		for i, candidate := range ranked {
+			amountOut, err := r.routeAmountOut(sdk.NewCoin(tokenIn.Denom, amountsIn[i].Add(slice)), candidate.route)
+			if err != nil {
+				continue
+			}
+			if gain := amountOut.Sub(amountsOut[i]); best == -1 || gain.GT(bestGain) {
+				best, bestGain, bestOut = i, gain, amountOut
+			}
+		}
+		if best == -1 {
+			return nil, sdk.Int{}, fmt.Errorf("no route can take the next %s%s", slice, tokenIn.Denom)
+		}
+
+		amountsIn[best] = amountsIn[best].Add(slice)
+		amountsOut[best] = bestOut
+		remaining = remaining.Sub(slice)
+	}
+
+	var splitRoutes []poolmanagertypes.SwapAmountInSplitRoute
+	totalOut := sdk.ZeroInt()
+	for i, candidate := range ranked {
+		if !amountsIn[i].IsPositive() {
+			continue
+		}
+		splitRoutes = append(splitRoutes, poolmanagertypes.SwapAmountInSplitRoute{
+			Pools:         candidate.route,
+			TokenInAmount: amountsIn[i],
+		})
+		totalOut = totalOut.Add(amountsOut[i])
+	}
+
+	return splitRoutes, totalOut, nil
+}
+
+// routeAmountOut returns what swapping tokenIn along route returns, charging the
+// taker fee on every hop the way the poolmanager does
+func (r *localRouter) routeAmountOut(tokenIn sdk.Coin, route localRoute) (sdk.Int, error) {
+	for _, hop := range route {
+		pool, ok := r.pools[hop.PoolId]
+		if !ok {
+			return sdk.Int{}, fmt.Errorf("pool %d is not loaded", hop.PoolId)
+		}
+
+		takerFee, err := r.takerFee(tokenIn.Denom, hop.TokenOutDenom)
+		if err != nil {
+			return sdk.Int{}, err
+		}
+		tokenInAfterTakerFee, _ := poolmanager.CalcTakerFeeExactIn(tokenIn, takerFee)
+
+		tokenOut, err := r.poolAmountOut(pool, tokenInAfterTakerFee, hop.TokenOutDenom)
+		if err != nil {
+			return sdk.Int{}, fmt.Errorf("error swapping through pool %d: %v", hop.PoolId, err)
+		}
+		tokenIn = tokenOut
+	}
+	return tokenIn.Amount, nil
+}
+
+// routeFeeRate returns the share of the amount in the pools of route charge in
+// taker fees and spread factors
+func (r *localRouter) routeFeeRate(tokenInDenom string, route []poolmanagertypes.SwapAmountInSplitRoute) (sdk.Dec, error) {
+	pathFeeRates := make([]sdk.Dec, len(route))
+	for i, path := range route {
+		var hopFeeRates []sdk.Dec
+		denomIn := tokenInDenom
+		for _, hop := range path.Pools {
+			pool, ok := r.pools[hop.PoolId]
+			if !ok {
+				return sdk.Dec{}, fmt.Errorf("pool %d is not loaded", hop.PoolId)
+			}
+			takerFee, err := r.takerFee(denomIn, hop.TokenOutDenom)
+			if err != nil {
+				return sdk.Dec{}, err
+			}
+			hopFeeRates = append(hopFeeRates, takerFee, pool.GetSpreadFactor(sdk.Context{}))
+			denomIn = hop.TokenOutDenom
+		}
+		pathFeeRates[i] = pathFeeRate(hopFeeRates)
+	}
+	return splitFeeRate(route, pathFeeRates), nil
+}
+
+// poolAmountOut computes a single pool swap with the chain's own pool math
+func (r *localRouter) poolAmountOut(pool poolmanagertypes.PoolI, tokenIn sdk.Coin, tokenOutDenom string) (sdk.Coin, error) {
+	// pool getters and the cfmm swap math do not read from the context
+	ctx := sdk.Context{}
+	switch pool := pool.(type) {
+	case *clmodel.Pool:
+		return r.concentratedAmountOut(pool, tokenIn, tokenOutDenom)
+	case gammtypes.CFMMPoolI:
+		return pool.CalcOutAmtGivenIn(ctx, sdk.NewCoins(tokenIn), tokenOutDenom, pool.GetSpreadFactor(ctx))
+	default:
+		return sdk.Coin{}, fmt.Errorf("unsupported pool type %s", pool.GetType())
+	}
+}
+
+// concentratedAmountOut walks the pool's initialized ticks in the swap direction
+// the way the concentrated liquidity module does, bucket by bucket
+func (r *localRouter) concentratedAmountOut(pool *clmodel.Pool, tokenIn sdk.Coin, tokenOutDenom string) (sdk.Coin, error) {
+	ticks, err := r.liquidityNet(pool.GetId(), tokenIn.Denom)
+	if err != nil {
+		return sdk.Coin{}, err
+	}
+
+	zeroForOne := tokenIn.Denom == pool.GetToken0()
+	sqrtPriceLimit, err := swapstrategy.GetSqrtPriceLimit(osmomath.ZeroBigDec(), zeroForOne)
+	if err != nil {
+		return sdk.Coin{}, err
+	}
+	strategy := swapstrategy.New(zeroForOne, sqrtPriceLimit, nil, pool.GetSpreadFactor(sdk.Context{}))
+
+	sqrtPrice := ticks.CurrentSqrtPrice
+	liquidity := ticks.CurrentLiquidity
+	amountRemaining := tokenIn.Amount.ToLegacyDec()
+	amountOut := osmomath.ZeroDec()
+
+	for i := 0; amountRemaining.GT(osmomath.SmallestDec()); i++ {
+		if i >= len(ticks.LiquidityDepths) {
+			return sdk.Coin{}, fmt.Errorf("not enough liquidity to swap %s", tokenIn)
+		}
+		nextTick := ticks.LiquidityDepths[i]
+
+		nextTickSqrtPrice, err := clmath.TickToSqrtPrice(nextTick.TickIndex)
+		if err != nil {
+			return sdk.Coin{}, err
+		}
+
+		computedSqrtPrice, amountIn, bucketAmountOut, spreadCharge := strategy.ComputeSwapWithinBucketOutGivenIn(
+			sqrtPrice,
+			strategy.GetSqrtTargetPrice(nextTickSqrtPrice),
+			liquidity,
+			amountRemaining,
+		)
+
+		sqrtPrice = computedSqrtPrice
+		amountRemaining = amountRemaining.Sub(amountIn.Add(spreadCharge))
+		amountOut = amountOut.Add(bucketAmountOut)
+
+		// the swap stopped within this bucket
+		if !nextTickSqrtPrice.Equal(computedSqrtPrice) {
+			break
+		}
+		liquidity = liquidity.Add(strategy.SetLiquidityDeltaSign(nextTick.LiquidityNet))
+	}
+
+	if amountRemaining.IsNegative() {
+		return sdk.Coin{}, fmt.Errorf("swap of %s overcharged by %s", tokenIn, amountRemaining.Neg())
+	}
+
+	return sdk.NewCoin(tokenOutDenom, amountOut.TruncateInt()), nil
+}
+
+// loadPoolsByDenom loads the pools holding denom that the router can swap through
+func (r *localRouter) loadPoolsByDenom(denom string) ([]poolmanagertypes.PoolI, error) {
+	res, err := r.poolClient.ListPoolsByDenom(context.Background(), &poolmanagerqueryproto.ListPoolsByDenomRequest{Denom: denom})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s pools: %v", denom, err)
+	}
+
+	var pools []poolmanagertypes.PoolI
+	for _, poolAny := range res.Pools {
+		var pool poolmanagertypes.PoolI
+		if err := r.seedConfig.EncodingConfig.InterfaceRegistry.UnpackAny(poolAny, &pool); err != nil {
+			return nil, fmt.Errorf("error decoding pool: %v", err)
+		}
+
+		switch pool.(type) {
+		case *clmodel.Pool, gammtypes.CFMMPoolI:
+		default:
+			continue
+		}
+
+		r.pools[pool.GetId()] = pool
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (r *localRouter) liquidityNet(poolID uint64, tokenInDenom string) (*clqueryproto.LiquidityNetInDirectionResponse, error) {
+	key := fmt.Sprintf("%d/%s", poolID, tokenInDenom)
+	if ticks, ok := r.ticks[key]; ok {
+		return ticks, nil
+	}
+
+	ticks, err := r.clClient.LiquidityNetInDirection(context.Background(), &clqueryproto.LiquidityNetInDirectionRequest{
+		PoolId:     poolID,
+		TokenIn:    tokenInDenom,
+		UseCurTick: true,
+		UseNoBound: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pool %d liquidity: %v", poolID, err)
+	}
+
+	r.ticks[key] = ticks
+	return ticks, nil
+}
+
+func (r *localRouter) takerFee(denomIn, denomOut string) (osmomath.Dec, error) {
+	key := denomIn + "/" + denomOut
+	if takerFee, ok := r.takerFees[key]; ok {
+		return takerFee, nil
+	}
+
+	res, err := r.poolClient.TradingPairTakerFee(context.Background(), &poolmanagerqueryproto.TradingPairTakerFeeRequest{
+		Denom_0: denomIn,
+		Denom_1: denomOut,
+	})
+	if err != nil {
+		return osmomath.Dec{}, fmt.Errorf("error fetching %s taker fee: %v", key, err)
+	}
+
+	r.takerFees[key] = res.TakerFee
+	return res.TakerFee, nil
+}
+
+func poolHasDenom(pool poolmanagertypes.PoolI, denom string) bool {
+	for _, poolDenom := range pool.GetPoolDenoms(sdk.Context{}) {
+		if poolDenom == denom {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler turns a stream of triggers, such as new blocks or order book
+// updates, into arb evaluations. Bursts of triggers are debounced into a
+// single evaluation and evaluations never overlap.
+type Scheduler struct {
+	debounce time.Duration
+	triggers chan string
+}
+
+func NewScheduler(debounce time.Duration) *Scheduler {
+	return &Scheduler{
+		debounce: debounce,
+		// a single slot, triggers arriving while one is pending are coalesced into it
+		triggers: make(chan string, 1),
+	}
+}
+
+// Trigger requests an evaluation, source is only used for logging. It never blocks.
+func (s *Scheduler) Trigger(source string) {
+	select {
+	case s.triggers <- source:
+	default:
+	}
+}
+
+// Run calls evaluate for every debounced trigger until ctx is cancelled.
+// evaluate runs on the calling goroutine, so a new arb is never started while
+// the previous one is still settling. Triggers received in the meantime result
+// in one more evaluation once it returns.
+func (s *Scheduler) Run(ctx context.Context, evaluate func(source string)) {
+	for {
+		var source string
+		select {
+		case <-ctx.Done():
+			return
+		case source = <-s.triggers:
+		}
+
+		// the window is not extended by later triggers, otherwise a busy
+		// order book stream would postpone the evaluation forever
+		timer := time.NewTimer(s.debounce)
+	debounce:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.triggers:
+			case <-timer.C:
+				break debounce
+			}
+		}
+
+		log.Println("Evaluating arb, triggered by", source)
+		evaluate(source)
+	}
+}
@@ -0,0 +1,237 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// arbCandidate is an arb in one direction priced at one size against the depth of both venues
+type arbCandidate struct {
+	direction ArbDirection
+	// amount is in human readable units of the base asset
+	amount sdk.Dec
+	// cexPrice and osmosisPrice are what each leg executes at for amount
+	cexPrice     sdk.Dec
+	osmosisPrice sdk.Dec
+	osmosisQuote OsmosisQuote
+	profit       arbProfit
+	// auctionBid is the top of block bid the swap is sent with, unset to send it
+	// on its own. It is sized once by estimateFixedCosts for the best candidate.
+	auctionBid sdk.Coin
+}
+
+// profitable reports whether the candidate makes a profit after the fees that
+// grow with its size, the costs of a single arb are only estimated for the best one
+func (c arbCandidate) profitable() bool {
+	return c.profit.variable().IsPositive()
+}
+
+// bidProfit is the profit the auction bid is sized from, what is left before the bid
+func (c arbCandidate) bidProfit() sdk.Dec {
+	return c.profit.variable().Sub(c.profit.gas)
+}
+
+// arbSizing is the outcome of sizing an arb in both directions
+type arbSizing struct {
+	// probes are both directions priced at their smallest size, used for reporting
+	probes map[ArbDirection]arbCandidate
+	// best is the most profitable candidate, unset when neither direction is profitable
+	best  arbCandidate
+	found bool
+}
+
+// sizeArb prices both directions of the pair on a ladder of sizes each venue can
+// fund and picks the size with the highest expected profit after fees. Each direction is
+// first priced at its smallest size, and only walked up the ladder when that is
+// profitable. The walk stops at the first size that earns less than the one
+// before, as slippage on both venues only grows with size.
+func sizeArb(seedConfig SeedConfig, venue CEXVenue, pair TradingPair, filters SymbolFilters, book OrderBook, balances Balances) (arbSizing, error) {
+	// the mid price is only used to size the trade, the decision is made on executable prices
+	midPrice, err := book.MidPrice()
+	if err != nil {
+		return arbSizing{}, fmt.Errorf("error pricing %s %s: %v", venue.Name(), pair.CEXSymbol, err)
+	}
+
+	fees, err := venue.GetTradingFees(pair.CEXSymbol)
+	if err != nil {
+		return arbSizing{}, fmt.Errorf("error fetching %s %s trading fees: %v", venue.Name(), pair.CEXSymbol, err)
+	}
+	cexFeeRate := fees.Rate(pair.HedgeOrderType)
+	hedgeFilters := filters.ForOrderType(pair.HedgeOrderType)
+
+	sizing := arbSizing{probes: make(map[ArbDirection]arbCandidate)}
+	var errs []error
+	for _, direction := range []ArbDirection{ArbDirectionBuyCEX, ArbDirectionSellCEX} {
+		maxAmount := maxArbAmount(pair, hedgeFilters, balances, venue.Name(), direction, midPrice)
+		sizes := arbSizes(pair, hedgeFilters, maxAmount)
+		if len(sizes) == 0 {
+			fmt.Println("Insufficient balance to", direction, pair.Name, ", at most", maxAmount, pair.CEXBaseAsset)
+			continue
+		}
+
+		candidate, err := priceArbCandidate(seedConfig, pair, filters, book, balances, venue.Name(), direction, sizes[0], midPrice, cexFeeRate)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", direction, err))
+			continue
+		}
+		sizing.probes[direction] = candidate
+		if !candidate.profitable() {
+			continue
+		}
+
+		for _, size := range sizes[1:] {
+			next, err := priceArbCandidate(seedConfig, pair, filters, book, balances, venue.Name(), direction, size, midPrice, cexFeeRate)
+			if err != nil {
+				fmt.Println("Stopped sizing", direction, "at", size, pair.CEXBaseAsset, ":", err)
+				break
+			}
+			if !next.profitable() || next.profit.variable().LTE(candidate.profit.variable()) {
+				break
+			}
+			candidate = next
+		}
+
+		if !sizing.found || candidate.profit.variable().GT(sizing.best.profit.variable()) {
+			sizing.best, sizing.found = candidate, true
+		}
+	}
+
+	if len(sizing.probes) == 0 {
+		if len(errs) > 0 {
+			return arbSizing{}, errors.Join(errs...)
+		}
+		return arbSizing{}, fmt.Errorf("insufficient balance for arbitrage")
+	}
+	for _, err := range errs {
+		fmt.Println("Error pricing", pair.Name, ":", err)
+	}
+	return sizing, nil
+}
+
+// hedgeQuantity returns the base an Osmosis swap returned, tokenOut in the base
+// denom's smallest unit, rounded down to a lot the CEX accepts
+func hedgeQuantity(pair TradingPair, filters SymbolFilters, tokenOut sdk.Int) sdk.Dec {
+	return filters.ForOrderType(pair.HedgeOrderType).RoundQuantity(fromBaseUnits(tokenOut, pair.BaseExponent))
+}
+
+// maxArbAmount returns the largest arb in direction both venues can fund, in
+// units of the base asset. Each venue spends at most the pair's arb percentage
+// of its free balance of the asset it gives up: in ArbDirectionBuyCEX the CEX
+// spends quote and Osmosis base, in ArbDirectionSellCEX the other way around.
+// The amount is also capped by the pair's max arb amount and max notional.
+func maxArbAmount(pair TradingPair, filters SymbolFilters, balances Balances, cexVenue string, direction ArbDirection, midPrice sdk.Dec) sdk.Dec {
+	var baseBalance, quoteBalance sdk.Dec
+	switch direction {
+	case ArbDirectionBuyCEX:
+		baseBalance = balances.FreeOn(osmosisVenue, pair.CEXBaseAsset)
+		quoteBalance = balances.FreeOn(cexVenue, pair.CEXQuoteAsset)
+	case ArbDirectionSellCEX:
+		baseBalance = balances.FreeOn(cexVenue, pair.CEXBaseAsset)
+		quoteBalance = balances.FreeOn(osmosisVenue, pair.CEXQuoteAsset)
+	default:
+		return sdk.ZeroDec()
+	}
+
+	amount := sdk.MinDec(baseBalance, quoteBalance.QuoTruncate(midPrice)).Mul(floatToDec(pair.ArbPercentage))
+	if pair.MaxArbAmount > 0 {
+		amount = sdk.MinDec(amount, floatToDec(pair.MaxArbAmount))
+	}
+	if pair.MaxNotional > 0 {
+		amount = sdk.MinDec(amount, floatToDec(pair.MaxNotional).QuoTruncate(midPrice))
+	}
+	return filters.RoundQuantity(truncateDec(amount, pair.BaseExponent))
+}
+
+// arbSizes splits maxAmount into arbSizingSteps ascending sizes, rounded down to
+// the base denom's smallest unit and a lot the CEX accepts, leaving out sizes
+// below the pair's min arb amount
+func arbSizes(pair TradingPair, filters SymbolFilters, maxAmount sdk.Dec) []sdk.Dec {
+	minAmount := floatToDec(pair.MinArbAmount)
+
+	var sizes []sdk.Dec
+	for step := int64(1); step <= arbSizingSteps; step++ {
+		size := filters.RoundQuantity(truncateDec(maxAmount.MulInt64(step).QuoInt64(arbSizingSteps), pair.BaseExponent))
+		if !size.IsPositive() || size.LT(minAmount) {
+			continue
+		}
+		if len(sizes) > 0 && size.Equal(sizes[len(sizes)-1]) {
+			continue
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// priceArbCandidate prices an arb of amount in direction against the CEX book
+// and an Osmosis quote, with the hedge paying cexFeeRate, failing when either
+// venue can't fund its leg. Buying base on Osmosis spends the quote worth amount
+// at midPrice, and the candidate is priced at the base that returns, which is
+// what the hedge sells.
+func priceArbCandidate(seedConfig SeedConfig, pair TradingPair, filters SymbolFilters, book OrderBook, balances Balances, cexVenue string, direction ArbDirection, amount, midPrice, cexFeeRate sdk.Dec) (arbCandidate, error) {
+	candidate := arbCandidate{direction: direction, amount: amount}
+	var osmosisNotional sdk.Dec
+
+	switch direction {
+	case ArbDirectionBuyCEX:
+		cexPrice, err := book.ExecutionPrice(OrderSideBuy, amount)
+		if err != nil {
+			return arbCandidate{}, fmt.Errorf("error pricing %s buy: %v", pair.CEXSymbol, err)
+		}
+		// the commission is charged on top of the notional when it is paid in quote
+		if free := balances.FreeOn(cexVenue, pair.CEXQuoteAsset); amount.Mul(cexPrice).Mul(sdk.OneDec().Add(cexFeeRate)).GT(free) {
+			return arbCandidate{}, fmt.Errorf("buying %s %s costs more than the %s %s free", amount, pair.CEXBaseAsset, free, pair.CEXQuoteAsset)
+		}
+
+		quote, err := GetOsmosisBaseToQuoteQuote(seedConfig, pair, amount)
+		if err != nil {
+			return arbCandidate{}, fmt.Errorf("error fetching Osmosis %s price: %v", pair.Name, err)
+		}
+		candidate.cexPrice, candidate.osmosisPrice, candidate.osmosisQuote = cexPrice, quote.Price, quote
+		osmosisNotional = fromBaseUnits(quote.TokenOutAmount, pair.QuoteExponent)
+
+	case ArbDirectionSellCEX:
+		// buying base on osmosis spends quote, so route the quote equivalent of amount
+		quote, err := GetOsmosisQuoteToBaseQuote(seedConfig, pair, amount.Mul(midPrice))
+		if err != nil {
+			return arbCandidate{}, fmt.Errorf("error fetching Osmosis %s price: %v", pair.Name, err)
+		}
+		if !quote.TokenOutAmount.IsPositive() {
+			return arbCandidate{}, fmt.Errorf("osmosis quoted no %s output", pair.Name)
+		}
+		quoteIn := fromBaseUnits(quote.TokenInAmount, pair.QuoteExponent)
+		if free := balances.FreeOn(osmosisVenue, pair.CEXQuoteAsset); quoteIn.GT(free) {
+			return arbCandidate{}, fmt.Errorf("buying %s %s on Osmosis costs more than the %s %s free", amount, pair.CEXBaseAsset, free, pair.CEXQuoteAsset)
+		}
+
+		// the hedge sells what the swap returns, not amount
+		candidate.amount = hedgeQuantity(pair, filters, quote.TokenOutAmount)
+		if !candidate.amount.IsPositive() {
+			return arbCandidate{}, fmt.Errorf("osmosis quoted %s%s, less than a lot of %s", quote.TokenOutAmount, pair.BaseDenom, pair.CEXSymbol)
+		}
+		if free := balances.FreeOn(cexVenue, pair.CEXBaseAsset); candidate.amount.GT(free) {
+			return arbCandidate{}, fmt.Errorf("selling %s %s costs more than the %s %s free", candidate.amount, pair.CEXBaseAsset, free, pair.CEXBaseAsset)
+		}
+		cexPrice, err := book.ExecutionPrice(OrderSideSell, candidate.amount)
+		if err != nil {
+			return arbCandidate{}, fmt.Errorf("error pricing %s sell: %v", pair.CEXSymbol, err)
+		}
+
+		// quote spent per base received, rounded up like the CEX buy price
+		osmosisPrice := quoteIn.QuoRoundUp(fromBaseUnits(quote.TokenOutAmount, pair.BaseExponent))
+		candidate.cexPrice, candidate.osmosisPrice, candidate.osmosisQuote = cexPrice, osmosisPrice, quote
+		// the share of the quote spent that bought the base hedged, what is left
+		// below a lot is kept as inventory
+		osmosisNotional = quoteIn.Mul(candidate.amount).Quo(fromBaseUnits(quote.TokenOutAmount, pair.BaseExponent))
+
+	default:
+		return arbCandidate{}, fmt.Errorf("invalid arb direction %s", direction)
+	}
+
+	if !candidate.cexPrice.IsPositive() || !candidate.osmosisPrice.IsPositive() {
+		return arbCandidate{}, fmt.Errorf("%s of %s %s priced at a non positive price", direction, candidate.amount, pair.CEXBaseAsset)
+	}
+	candidate.profit = newArbProfit(direction, candidate.amount, candidate.cexPrice, osmosisNotional, candidate.osmosisQuote.FeeRate, cexFeeRate)
+	return candidate, nil
+}